@@ -2,14 +2,18 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 
 	"pagination/storage"
 	"pagination/storage/dynamo"
+	"pagination/storage/memory"
 )
 
 type statsHandler struct {
@@ -27,25 +31,21 @@ const (
 	testPlayerCountry2      = "USA"
 	userEnforcedRecordLimit = 1
 	goalThreshold           = 100
+	tableSetupTimeout       = 2 * time.Minute
+	// signingKeysEnv holds the page token signing keys as comma separated
+	// id:secret pairs; the first one signs new tokens
+	signingKeysEnv = "PAGE_TOKEN_SIGNING_KEYS"
 )
 
 func main() {
+	inMemory := flag.Bool("memory", false, "use the in-memory backend instead of DynamoDB Local")
+	flag.Parse()
 
-	cfg, err := config.LoadDefaultConfig(context.TODO(), func(o *config.LoadOptions) error {
-		o.Region = AwsRegion
-		return nil
-	})
-
-	if err != nil {
-		os.Exit(1)
+	stats := statsHandler{storageClient: memory.New()}
+	if !*inMemory {
+		stats.storageClient = newDynamoStorage()
 	}
 
-	svc := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
-		o.EndpointResolver = dynamodb.EndpointResolverFromURL(DynamoLocalUrl)
-	})
-	storage := dynamo.New(svc)
-	stats := statsHandler{storageClient: storage}
-
 	stats.insertSeedData()
 
 	fmt.Println("getting single player stats")
@@ -54,22 +54,81 @@ func main() {
 	stats.ListPlayersWithoutPagination()
 	fmt.Println("listing player stats while handling internal pagination")
 	stats.ListAllPlayers()
+	fmt.Println("counting player stats")
+	stats.CountPlayers()
 	fmt.Println("listing limited number player stats")
 	stats.ListLimitedPlayers()
 	fmt.Println("listing limited number player stats and apply goals scored filter")
 	stats.ListPlayersByGoalsThreshold()
 	fmt.Println("listing limited number player stats with goals filter in descending order wrt goals scored")
 	stats.ListPlayersByGoalsThresholdSorted()
+	fmt.Println("querying player stats with goals filter on the index chosen by the planner")
+	stats.QueryPlayers()
+}
+
+func newDynamoStorage() storage.Storage {
+	cfg, err := config.LoadDefaultConfig(context.TODO(), func(o *config.LoadOptions) error {
+		o.Region = AwsRegion
+		return nil
+	})
+
+	if err != nil {
+		os.Exit(1)
+	}
+
+	svc := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		o.EndpointResolver = dynamodb.EndpointResolverFromURL(DynamoLocalUrl)
+	})
+	keys, err := signingKeys(os.Getenv(signingKeysEnv))
+	if err != nil {
+		fmt.Println("invalid", signingKeysEnv, err)
+		os.Exit(1)
+	}
+	var opts []dynamo.Option
+	if len(keys) == 0 {
+		fmt.Fprintf(os.Stderr, "WARNING: %s is not set; page tokens are signed with a random key and stop working when this process exits\n", signingKeysEnv)
+	} else {
+		opts = append(opts, dynamo.WithSigningKeys(keys...))
+	}
+	storageClient := dynamo.New(svc, opts...)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), tableSetupTimeout)
+	defer cancel()
+	if err := storageClient.CreateTable(ctx); err != nil {
+		fmt.Println("failed to create stats table", err)
+		os.Exit(1)
+	}
+	if err := storageClient.VerifySchema(ctx); err != nil {
+		fmt.Println("stats table schema check failed", err)
+		os.Exit(1)
+	}
+	return storageClient
+}
+
+// signingKeys parses the id:secret pairs of value, which may be empty.
+func signingKeys(value string) ([]dynamo.SigningKey, error) {
+	var keys []dynamo.SigningKey
+	for i, pair := range strings.Split(value, ",") {
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			// the pair itself is not echoed, as it may hold a secret
+			return nil, fmt.Errorf("signing key %d is not an id:secret pair", i+1)
+		}
+		keys = append(keys, dynamo.SigningKey{ID: parts[0], Secret: []byte(parts[1])})
+	}
+	return keys, nil
 }
 
 func (s *statsHandler) insertSeedData() {
-	cursor := &dynamo.Cursor{PageLimit: 1, ScanIndexForward: true}
-	resp, err := s.storageClient.ScanStatsTable(context.TODO(), cursor)
+	resp, err := s.storageClient.ScanStatsTable(context.TODO(), storage.ScanFilter{}, storage.PageRequest{PageLimit: 1, ScanIndexForward: true})
 	if err != nil {
 		fmt.Println("failed to scan stats table", err)
 		os.Exit(1)
 	}
-	if len(resp) != 0 {
+	if len(resp.Records) != 0 {
 		fmt.Println("not inserting seed data as stats table already contains items")
 		return
 	}
@@ -86,7 +145,7 @@ func (s *statsHandler) insertSeedData() {
 }
 
 func (s *statsHandler) ListPlayersWithoutPagination() {
-	resp, err := s.storageClient.ListPlayers(context.TODO(), testPlayerCountry2, womenNationalTeam)
+	resp, err := s.storageClient.ListPlayers(context.TODO(), testPlayerCountry2, womenNationalTeam, storage.ReadOptions{})
 	if err != nil {
 		fmt.Println("failed while listing player stats without pagination support : ", err)
 		os.Exit(1)
@@ -96,7 +155,7 @@ func (s *statsHandler) ListPlayersWithoutPagination() {
 }
 
 func (s *statsHandler) ListAllPlayers() {
-	resp, err := s.storageClient.ListAllPlayers(context.TODO(), testPlayerCountry2, womenNationalTeam)
+	resp, err := s.storageClient.ListAllPlayers(context.TODO(), testPlayerCountry2, womenNationalTeam, storage.ReadOptions{})
 	if err != nil {
 		fmt.Println("failed while listing player stats with pagination support : ", err)
 		os.Exit(1)
@@ -105,84 +164,69 @@ func (s *statsHandler) ListAllPlayers() {
 	}
 }
 
-func (s *statsHandler) ListLimitedPlayers() {
-	cursor := &dynamo.Cursor{PageLimit: userEnforcedRecordLimit, ScanIndexForward: true}
-
-	// Iterating over the result pages
-	pageCount := 0
-	isFirstPage := true
-	for {
-		if cursor.LastEvaluatedKey == nil && !isFirstPage {
-			break
-		}
-		pageCount += 1
-		resp, err := s.storageClient.ListLimitedPlayers(context.TODO(), testPlayerCountry2, womenNationalTeam, cursor)
-		if err != nil {
-			fmt.Println("failed while listing limit specified number of player stats : ", err)
-			os.Exit(1)
-		} else {
-			if len(resp) != 0 {
-				fmt.Println("Page Number : ", pageCount)
-				PrintRecords(resp)
-			}
-		}
-		isFirstPage = false
+func (s *statsHandler) CountPlayers() {
+	resp, err := s.storageClient.CountPlayers(context.TODO(), testPlayerCountry2, womenNationalTeam, storage.CountOptions{})
+	if err != nil {
+		fmt.Println("failed while counting player stats : ", err)
+		os.Exit(1)
 	}
+	fmt.Println("Player Count : ", resp.Count)
 }
 
-func (s *statsHandler) ListPlayersByGoalsThreshold() {
-	cursor := &dynamo.Cursor{PageLimit: 2, ScanIndexForward: true}
+func (s *statsHandler) ListLimitedPlayers() {
+	pager := storage.NewPager(func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+		return s.storageClient.ListLimitedPlayers(ctx, testPlayerCountry2, womenNationalTeam, page)
+	}, storage.PageRequest{PageLimit: userEnforcedRecordLimit, ScanIndexForward: true, LookAhead: true})
+	printPages(pager, "failed while listing limit specified number of player stats : ")
+}
 
-	// Iterating over the result pages
-	pageCount := 0
-	isFirstPage := true
-	for {
-		if cursor.LastEvaluatedKey == nil && !isFirstPage {
-			break
-		}
-		pageCount += 1
-		resp, err := s.storageClient.ListPlayersByGoalsThreshold(context.TODO(), testPlayerCountry2, womenNationalTeam, goalThreshold, cursor)
-		if err != nil {
-			fmt.Println("failed while listing limit specified number of player stats with goals filter : ", err)
-			os.Exit(1)
-		} else {
-			if len(resp) != 0 {
-				fmt.Println("Page Number : ", pageCount)
-				PrintRecords(resp)
-			}
-		}
-		isFirstPage = false
-	}
+func (s *statsHandler) ListPlayersByGoalsThreshold() {
+	pager := storage.NewPager(func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+		return s.storageClient.ListPlayersByGoalsThreshold(ctx, testPlayerCountry2, womenNationalTeam, goalThreshold, page)
+	}, storage.PageRequest{PageLimit: 2, ScanIndexForward: true, LookAhead: true})
+	printPages(pager, "failed while listing limit specified number of player stats with goals filter : ")
 }
 
 func (s *statsHandler) ListPlayersByGoalsThresholdSorted() {
+	reducedGoalThreshold := goalThreshold - 50
 	// ScanIndexForward = false for top scorers in descending order
-	cursor := &dynamo.Cursor{PageLimit: 1, ScanIndexForward: false}
+	pager := storage.NewPager(func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+		return s.storageClient.ListPlayersByGoalsThresholdSorted(ctx, testPlayerCountry2, womenNationalTeam, reducedGoalThreshold, page)
+	}, storage.PageRequest{PageLimit: 1, ScanIndexForward: false, LookAhead: true})
+	printPages(pager, "failed while listing limit specified number of player stats with goals filter in sorted order : ")
+}
+
+func (s *statsHandler) QueryPlayers() {
+	query := storage.PlayerQuery{Country: testPlayerCountry2, TeamPrefix: womenNationalTeam, Goals: storage.Between(goalThreshold-50, goalThreshold+50)}
+	plan, err := s.storageClient.PlanPlayerQuery(query, storage.ReadOptions{})
+	if err != nil {
+		fmt.Println("failed while planning player stats query : ", err)
+		os.Exit(1)
+	}
+	fmt.Println("Plan : ", plan)
+	pager := storage.NewPager(func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+		return s.storageClient.QueryPlayers(ctx, query, page)
+	}, storage.PageRequest{PageLimit: 1, LookAhead: true})
+	printPages(pager, "failed while querying player stats : ")
+}
+
+// printPages prints every page of the pager, exiting with failureMessage if
+// fetching a page fails.
+func printPages(pager *storage.Pager, failureMessage string) {
 	pageCount := 0
-	reducedGoalThreshold := goalThreshold - 50
-	// Iterating over the result pages
-	isFirstPage := true
-	for {
-		if cursor.LastEvaluatedKey == nil && !isFirstPage {
-			break
-		}
+	for page := range pager.Pages(context.TODO()) {
 		pageCount += 1
-		resp, err := s.storageClient.ListPlayersByGoalsThresholdSorted(context.TODO(), testPlayerCountry2, womenNationalTeam, reducedGoalThreshold, cursor)
-		if err != nil {
-			fmt.Println("failed while listing limit specified number of player stats with goals filter in sorted order : ", err)
-			os.Exit(1)
-		} else {
-			if len(resp) != 0 {
-				fmt.Println("Page Number : ", pageCount)
-				PrintRecords(resp)
-			}
-		}
-		isFirstPage = false
+		fmt.Println("Page Number : ", pageCount)
+		PrintRecords(page.Records)
+	}
+	if err := pager.Err(); err != nil {
+		fmt.Println(failureMessage, err)
+		os.Exit(1)
 	}
 }
 
 func (s *statsHandler) GetPlayerStats() {
-	resp, err := s.storageClient.GetPlayerStats(context.TODO(), testPlayerCountry1, menNationalTeam, testPlayerFirstName, testPlayerLastName)
+	resp, err := s.storageClient.GetPlayerStats(context.TODO(), testPlayerCountry1, menNationalTeam, testPlayerFirstName, testPlayerLastName, storage.ReadOptions{})
 	if err != nil {
 		fmt.Println("failed while fetching player stats : ", err)
 		os.Exit(1)
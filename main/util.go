@@ -2,10 +2,10 @@ package main
 
 import (
 	"fmt"
-	"pagination/storage/dynamo"
+	"pagination/storage"
 )
 
-func PrintRecords(records []*dynamo.StatsRecord) {
+func PrintRecords(records []*storage.StatsRecord) {
 	for _, record := range records {
 		if record != nil {
 			fmt.Printf("%+v\n", *record)
@@ -13,11 +13,9 @@ func PrintRecords(records []*dynamo.StatsRecord) {
 	}
 }
 
-func LoadSeedDataInMemory() []dynamo.StatsRecord {
-	seedRecords := []dynamo.StatsRecord{
+func LoadSeedDataInMemory() []storage.StatsRecord {
+	seedRecords := []storage.StatsRecord{
 		{
-			PartitionKey: "Portugal",
-			SortKey:      "MNT#Cristiano#Ronaldo",
 			Goals:        118,
 			Assists:      43,
 			Appearances:  196,
@@ -27,8 +25,6 @@ func LoadSeedDataInMemory() []dynamo.StatsRecord {
 			LastName:     "Ronaldo",
 		},
 		{
-			PartitionKey: "Argentina",
-			SortKey:      "MNT#Lionel#Messi",
 			Goals:        98,
 			Assists:      55,
 			Appearances:  172,
@@ -38,8 +34,6 @@ func LoadSeedDataInMemory() []dynamo.StatsRecord {
 			LastName:     "Messi",
 		},
 		{
-			PartitionKey: "India",
-			SortKey:      "MNT#Sunil#Chhetri",
 			Goals:        84,
 			Assists:      11,
 			Appearances:  131,
@@ -49,8 +43,6 @@ func LoadSeedDataInMemory() []dynamo.StatsRecord {
 			LastName:     "Chhetri",
 		},
 		{
-			PartitionKey: "USA",
-			SortKey:      "WNT#Megan#Rapinoe",
 			Goals:        63,
 			Assists:      73,
 			Appearances:  197,
@@ -60,8 +52,6 @@ func LoadSeedDataInMemory() []dynamo.StatsRecord {
 			LastName:     "Rapinoe",
 		},
 		{
-			PartitionKey: "USA",
-			SortKey:      "WNT#Alex#Morgan",
 			Goals:        119,
 			Assists:      47,
 			Appearances:  200,
@@ -71,8 +61,6 @@ func LoadSeedDataInMemory() []dynamo.StatsRecord {
 			LastName:     "Morgan",
 		},
 		{
-			PartitionKey: "Egypt",
-			SortKey:      "MNT#Mohamed#Salah",
 			Goals:        47,
 			Assists:      26,
 			Appearances:  85,
@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"errors"
+)
+
+// ErrConsistentReadOnIndex is returned when a strongly consistent read is asked
+// of a secondary index, which only serves eventually consistent reads.
+var ErrConsistentReadOnIndex = errors.New("strongly consistent reads are not supported on secondary indexes")
+
+// ReadOptions configures the reads that take neither a PageRequest nor
+// CountOptions. ConsistentRead and Explain have the same meaning as in
+// PageRequest.
+type ReadOptions struct {
+	ConsistentRead bool
+	Explain        Explainer
+}
@@ -1,26 +1,123 @@
 package dynamo
 
 import (
+	"context"
+	"time"
+
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"pagination/storage"
+)
+
+var (
+	_ storage.Storage = (*Dynamo)(nil)
+	_ DynamoDBAPI     = (*dynamodb.Client)(nil)
 )
 
+// DynamoDBAPI is the part of *dynamodb.Client the package relies on. Accepting
+// it instead of the concrete client lets tests, recorders and middleware wrap
+// the client. Calls the package starts to use, such as batch or transaction
+// operations, are added here.
+type DynamoDBAPI interface {
+	dynamodb.QueryAPIClient
+	dynamodb.ScanAPIClient
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	dynamodb.DescribeTableAPIClient
+	CreateTable(ctx context.Context, params *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	UpdateTable(ctx context.Context, params *dynamodb.UpdateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error)
+}
+
 type Dynamo struct {
-	client *dynamodb.Client
+	client DynamoDBAPI
 	playerStats
 }
 
-type Cursor struct {
-	PageLimit        int32
-	LastEvaluatedKey map[string]types.AttributeValue
-	ScanIndexForward bool
+type Option func(*Dynamo)
+
+// WithSigningKeys signs page tokens with the first key and accepts tokens
+// signed by any of the keys, so a new key can be rolled out ahead of retiring
+// the old one.
+func WithSigningKeys(keys ...SigningKey) Option {
+	return func(d *Dynamo) {
+		d.tokens.keys = keys
+	}
+}
+
+// WithPageTokenTTL sets how long issued page tokens stay valid; zero disables
+// expiry.
+func WithPageTokenTTL(ttl time.Duration) Option {
+	return func(d *Dynamo) {
+		d.tokens.ttl = ttl
+	}
+}
+
+// WithTableName replaces the default player_stats_v1 table name.
+func WithTableName(name string) Option {
+	return func(d *Dynamo) {
+		d.tableName = name
+	}
+}
+
+// WithTablePrefix is prepended to the table name, e.g. "dev_" to run against
+// dev_player_stats_v1.
+func WithTablePrefix(prefix string) Option {
+	return func(d *Dynamo) {
+		d.tablePrefix = prefix
+	}
+}
+
+// WithIndexName replaces the default GSI1 name of the goals index.
+func WithIndexName(name string) Option {
+	return func(d *Dynamo) {
+		d.indexName = name
+	}
+}
+
+// WithKeyAttributes sets the names of the table's partition and sort key
+// attributes.
+func WithKeyAttributes(partitionKey string, sortKey string) Option {
+	return func(d *Dynamo) {
+		d.keys.partitionKey = partitionKey
+		d.keys.sortKey = sortKey
+	}
 }
 
-func New(client *dynamodb.Client) *Dynamo {
-	return &Dynamo{
+// WithGoalsAttribute sets the name of the goals attribute, which is the sort
+// key of the goals index.
+func WithGoalsAttribute(name string) Option {
+	return func(d *Dynamo) {
+		d.keys.goals = name
+	}
+}
+
+// New returns a Dynamo backed by client. Without WithSigningKeys, page tokens
+// are signed with a random key generated here, so they are only accepted by
+// this instance; deployments that serve one pagination from several instances
+// or across restarts configure shared keys.
+func New(client DynamoDBAPI, opts ...Option) *Dynamo {
+	d := &Dynamo{
 		client: client,
 		playerStats: playerStats{
 			dbClient: client,
+			tokens: &pageTokenCodec{
+				ttl: defaultPageTokenTTL,
+				now: time.Now,
+			},
+			tableName: defaultTableName,
+			indexName: defaultIndexName,
+			keys: keyAttributes{
+				partitionKey: pk,
+				sortKey:      sk,
+				goals:        goals,
+			},
 		},
 	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if len(d.tokens.keys) == 0 {
+		d.tokens.keys = []SigningKey{randomSigningKey()}
+	}
+	return d
 }
@@ -0,0 +1,108 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"pagination/storage"
+)
+
+// CountPlayers counts the players of a national team with the same key
+// condition as ListPlayers.
+func (p *playerStats) CountPlayers(ctx context.Context, country string, nationalTeam string, opts storage.CountOptions) (*storage.Count, error) {
+	expr, err := p.buildListPlayersQueryExpression(country, nationalTeam)
+	if err != nil {
+		return nil, err
+	}
+	return p.count(ctx, &dynamodb.QueryInput{
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		TableName:                 aws.String(p.table()),
+	}, opts)
+}
+
+// CountPlayersByGoalsThreshold counts the players ListPlayersByGoalsThreshold
+// returns. It queries the goals index, which only reads the players above the
+// threshold, unless opts asks for strongly consistent reads, which only the
+// table serves.
+func (p *playerStats) CountPlayersByGoalsThreshold(ctx context.Context, country string, nationalTeam string, goalThreshold int, opts storage.CountOptions) (*storage.Count, error) {
+	if opts.ConsistentRead {
+		expr, err := p.buildListPlayersWithGoalsFilterQueryExpression(country, nationalTeam, goalThreshold)
+		if err != nil {
+			return nil, err
+		}
+		return p.count(ctx, &dynamodb.QueryInput{
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			KeyConditionExpression:    expr.KeyCondition(),
+			FilterExpression:          expr.Filter(),
+			TableName:                 aws.String(p.table()),
+		}, opts)
+	}
+	// the list matches the team as a prefix of the table's sort key, which
+	// the index projects, so the count filters on the same attribute
+	builder := expression.NewBuilder().WithKeyCondition(p.goalsKeyCondition(country, storage.AtLeast(goalThreshold)))
+	if nationalTeam != "" {
+		builder = builder.WithFilter(expression.Name(p.keys.sortKey).BeginsWith(nationalTeam))
+	}
+	expr, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	return p.count(ctx, &dynamodb.QueryInput{
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		IndexName:                 aws.String(p.indexName),
+		TableName:                 aws.String(p.table()),
+	}, opts)
+}
+
+// count runs queryInput with Select=COUNT, following LastEvaluatedKey until the
+// results or the read budget run out.
+func (p *playerStats) count(ctx context.Context, queryInput *dynamodb.QueryInput, opts storage.CountOptions) (*storage.Count, error) {
+	if opts.MaxScanned < 0 || opts.MaxReadCapacity < 0 {
+		return nil, fmt.Errorf("read budgets must not be negative, got %d items and %g capacity units", opts.MaxScanned, opts.MaxReadCapacity)
+	}
+	consistent, err := consistentRead(opts.ConsistentRead, aws.ToString(queryInput.IndexName))
+	if err != nil {
+		return nil, err
+	}
+	client, err := p.client(opts.Explain)
+	if err != nil {
+		return nil, err
+	}
+	queryInput.ConsistentRead = consistent
+	queryInput.Select = types.SelectCount
+	queryInput.ReturnConsumedCapacity = types.ReturnConsumedCapacityIndexes
+	result := &storage.Count{Exact: true}
+	for {
+		queryInput.Limit = nil
+		if opts.MaxScanned > 0 {
+			queryInput.Limit = aws.Int32(opts.MaxScanned - int32(result.ScannedCount))
+		}
+		resp, err := client.Query(ctx, queryInput)
+		if err != nil {
+			return nil, err
+		}
+		result.Count += int64(resp.Count)
+		result.ScannedCount += int64(resp.ScannedCount)
+		result.ConsumedCapacity = addConsumedCapacity(result.ConsumedCapacity, resp.ConsumedCapacity)
+		if resp.LastEvaluatedKey == nil {
+			return result, nil
+		}
+		if opts.MaxScanned > 0 && result.ScannedCount >= int64(opts.MaxScanned) ||
+			opts.MaxReadCapacity > 0 && result.ConsumedCapacity != nil && result.ConsumedCapacity.CapacityUnits >= opts.MaxReadCapacity {
+			result.Exact = false
+			return result, nil
+		}
+		queryInput.ExclusiveStartKey = resp.LastEvaluatedKey
+	}
+}
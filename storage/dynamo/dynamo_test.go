@@ -0,0 +1,37 @@
+package dynamo_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"pagination/storage"
+	"pagination/storage/dynamo"
+	"pagination/storage/storagetest"
+)
+
+// The conformance suite needs a running DynamoDB with the stats table and GSI1
+// created, e.g. DynamoDB Local set up by scripts/soccer_app_create_table.sh:
+//
+//	DYNAMODB_ENDPOINT=http://localhost:8000 go test ./storage/dynamo/
+const endpointEnv = "DYNAMODB_ENDPOINT"
+
+func TestConformance(t *testing.T) {
+	endpoint := os.Getenv(endpointEnv)
+	if endpoint == "" {
+		t.Skipf("%s not set", endpointEnv)
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatalf("loading AWS config: %v", err)
+	}
+	client := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		o.EndpointResolver = dynamodb.EndpointResolverFromURL(endpoint)
+	})
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return dynamo.New(client)
+	}, storagetest.WithTokenForger(dynamo.ForgePageToken))
+}
@@ -0,0 +1,261 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"pagination/storage"
+)
+
+// Explanation is the storage.Explainer of this backend. Set as the Explain
+// option of a StatsReader call, it collects the requests the call sends. With
+// DryRun, reads are not sent at all: each is answered with an empty result, so
+// a paginated call records the first request it would make and returns an
+// empty page, and GetPlayerStats returns storage.ErrNotFound. The zero value
+// records requests as they are sent.
+type Explanation struct {
+	DryRun bool
+
+	mu       sync.Mutex
+	requests []ExplainedRequest
+}
+
+// ExplainedRequest is a single read request as sent to DynamoDB, together with
+// what it returned. The result fields are zero in dry runs.
+type ExplainedRequest struct {
+	Operation                 string // Query, Scan or GetItem
+	TableName                 string
+	IndexName                 string
+	KeyConditionExpression    string
+	FilterExpression          string
+	ProjectionExpression      string
+	ExpressionAttributeNames  map[string]string
+	ExpressionAttributeValues map[string]types.AttributeValue
+	Key                       map[string]types.AttributeValue // GetItem only
+	ExclusiveStartKey         map[string]types.AttributeValue
+	Limit                     int32
+	ScanIndexForward          bool
+	ConsistentRead            bool
+
+	Count            int32
+	ScannedCount     int32
+	ConsumedCapacity float64
+}
+
+// Requests returns the recorded requests in the order they were sent.
+func (e *Explanation) Requests() []ExplainedRequest {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]ExplainedRequest(nil), e.requests...)
+}
+
+func (e *Explanation) record(request ExplainedRequest) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests = append(e.requests, request)
+}
+
+func (e *Explanation) String() string {
+	var b strings.Builder
+	var count, scanned int32
+	var capacity float64
+	requests := e.Requests()
+	for i, r := range requests {
+		fmt.Fprintf(&b, "request %d: %s\n", i+1, r)
+		count += r.Count
+		scanned += r.ScannedCount
+		capacity += r.ConsumedCapacity
+	}
+	if e.DryRun {
+		b.WriteString("dry run, nothing was sent\n")
+	} else {
+		fmt.Fprintf(&b, "%d requests, %d of %d scanned items matched, %g capacity units consumed\n", len(requests), count, scanned, capacity)
+	}
+	return b.String()
+}
+
+func (r ExplainedRequest) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", r.Operation, r.TableName)
+	if r.IndexName != "" {
+		fmt.Fprintf(&b, " index %s", r.IndexName)
+	}
+	if r.KeyConditionExpression != "" {
+		fmt.Fprintf(&b, " key condition %q", r.KeyConditionExpression)
+	}
+	if r.FilterExpression != "" {
+		fmt.Fprintf(&b, " filter %q", r.FilterExpression)
+	}
+	if r.ProjectionExpression != "" {
+		fmt.Fprintf(&b, " projection %q", r.ProjectionExpression)
+	}
+	if len(r.ExpressionAttributeNames) > 0 {
+		names := make([]string, 0, len(r.ExpressionAttributeNames))
+		for placeholder, name := range r.ExpressionAttributeNames {
+			names = append(names, placeholder+"="+name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(&b, " names {%s}", strings.Join(names, " "))
+	}
+	if len(r.ExpressionAttributeValues) > 0 {
+		fmt.Fprintf(&b, " values %s", formatAttributes(r.ExpressionAttributeValues))
+	}
+	if r.Key != nil {
+		fmt.Fprintf(&b, " key %s", formatAttributes(r.Key))
+	}
+	if r.ExclusiveStartKey != nil {
+		fmt.Fprintf(&b, " start after %s", formatAttributes(r.ExclusiveStartKey))
+	}
+	if r.Limit > 0 {
+		fmt.Fprintf(&b, " limit %d", r.Limit)
+	}
+	if r.Operation == "Query" && !r.ScanIndexForward {
+		b.WriteString(" descending")
+	}
+	if r.ConsistentRead {
+		b.WriteString(" consistent")
+	}
+	if r.ScannedCount > 0 || r.Count > 0 {
+		fmt.Fprintf(&b, " -> %d of %d matched", r.Count, r.ScannedCount)
+	}
+	return b.String()
+}
+
+func formatAttributes(attributes map[string]types.AttributeValue) string {
+	parts := make([]string, 0, len(attributes))
+	for name, value := range attributes {
+		parts = append(parts, name+"="+formatAttribute(value))
+	}
+	sort.Strings(parts)
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+func formatAttribute(value types.AttributeValue) string {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		return fmt.Sprintf("%q", v.Value)
+	case *types.AttributeValueMemberN:
+		return v.Value
+	case *types.AttributeValueMemberB:
+		return fmt.Sprintf("0x%x", v.Value)
+	case *types.AttributeValueMemberBOOL:
+		return fmt.Sprint(v.Value)
+	case *types.AttributeValueMemberNULL:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// explainingClient records the reads of a call into its explanation and
+// passes everything else through.
+type explainingClient struct {
+	DynamoDBAPI
+	explanation *Explanation
+}
+
+// client returns the client for a read with the given Explain option: the
+// plain client without one, or one that records into it.
+func (p *playerStats) client(explain storage.Explainer) (DynamoDBAPI, error) {
+	if explain == nil {
+		return p.dbClient, nil
+	}
+	e, ok := explain.(*Explanation)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", storage.ErrExplainUnsupported, explain)
+	}
+	return &explainingClient{DynamoDBAPI: p.dbClient, explanation: e}, nil
+}
+
+func (c *explainingClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	request := ExplainedRequest{
+		Operation:                 "Query",
+		TableName:                 aws.ToString(params.TableName),
+		IndexName:                 aws.ToString(params.IndexName),
+		KeyConditionExpression:    aws.ToString(params.KeyConditionExpression),
+		FilterExpression:          aws.ToString(params.FilterExpression),
+		ProjectionExpression:      aws.ToString(params.ProjectionExpression),
+		ExpressionAttributeNames:  params.ExpressionAttributeNames,
+		ExpressionAttributeValues: params.ExpressionAttributeValues,
+		ExclusiveStartKey:         params.ExclusiveStartKey,
+		Limit:                     aws.ToInt32(params.Limit),
+		ScanIndexForward:          params.ScanIndexForward == nil || *params.ScanIndexForward,
+		ConsistentRead:            aws.ToBool(params.ConsistentRead),
+	}
+	if c.explanation.DryRun {
+		c.explanation.record(request)
+		return &dynamodb.QueryOutput{}, nil
+	}
+	out, err := c.DynamoDBAPI.Query(ctx, params, optFns...)
+	if err == nil {
+		request.Count, request.ScannedCount = out.Count, out.ScannedCount
+		request.ConsumedCapacity = capacityUnits(out.ConsumedCapacity)
+	}
+	c.explanation.record(request)
+	return out, err
+}
+
+func (c *explainingClient) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	request := ExplainedRequest{
+		Operation:                 "Scan",
+		TableName:                 aws.ToString(params.TableName),
+		IndexName:                 aws.ToString(params.IndexName),
+		FilterExpression:          aws.ToString(params.FilterExpression),
+		ProjectionExpression:      aws.ToString(params.ProjectionExpression),
+		ExpressionAttributeNames:  params.ExpressionAttributeNames,
+		ExpressionAttributeValues: params.ExpressionAttributeValues,
+		ExclusiveStartKey:         params.ExclusiveStartKey,
+		Limit:                     aws.ToInt32(params.Limit),
+		ScanIndexForward:          true,
+		ConsistentRead:            aws.ToBool(params.ConsistentRead),
+	}
+	if c.explanation.DryRun {
+		c.explanation.record(request)
+		return &dynamodb.ScanOutput{}, nil
+	}
+	out, err := c.DynamoDBAPI.Scan(ctx, params, optFns...)
+	if err == nil {
+		request.Count, request.ScannedCount = out.Count, out.ScannedCount
+		request.ConsumedCapacity = capacityUnits(out.ConsumedCapacity)
+	}
+	c.explanation.record(request)
+	return out, err
+}
+
+func (c *explainingClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	request := ExplainedRequest{
+		Operation:                "GetItem",
+		TableName:                aws.ToString(params.TableName),
+		ProjectionExpression:     aws.ToString(params.ProjectionExpression),
+		ExpressionAttributeNames: params.ExpressionAttributeNames,
+		Key:                      params.Key,
+		ScanIndexForward:         true,
+		ConsistentRead:           aws.ToBool(params.ConsistentRead),
+	}
+	if c.explanation.DryRun {
+		c.explanation.record(request)
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	out, err := c.DynamoDBAPI.GetItem(ctx, params, optFns...)
+	if err == nil {
+		if out.Item != nil {
+			request.Count, request.ScannedCount = 1, 1
+		}
+		request.ConsumedCapacity = capacityUnits(out.ConsumedCapacity)
+	}
+	c.explanation.record(request)
+	return out, err
+}
+
+func capacityUnits(consumed *types.ConsumedCapacity) float64 {
+	if consumed == nil {
+		return 0
+	}
+	return aws.ToFloat64(consumed.CapacityUnits)
+}
@@ -0,0 +1,73 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"pagination/storage"
+)
+
+func TestExplainDryRun(t *testing.T) {
+	client := &fakeClient{}
+	explanation := &Explanation{DryRun: true}
+	page, err := New(client).ListPlayersByGoalsThresholdSorted(context.Background(), "USA", "WNT", 100, storage.PageRequest{PageLimit: 5, Explain: explanation})
+	if err != nil {
+		t.Fatalf("ListPlayersByGoalsThresholdSorted: %v", err)
+	}
+	if len(client.queries) != 0 {
+		t.Errorf("dry run sent %d queries", len(client.queries))
+	}
+	if len(page.Records) != 0 || page.NextPageToken != "" {
+		t.Errorf("dry run returned %+v, want an empty last page", page)
+	}
+	requests := explanation.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("recorded %d requests, want 1", len(requests))
+	}
+	request := requests[0]
+	if request.Operation != "Query" || request.IndexName != defaultIndexName || request.Limit != 5 || request.ScanIndexForward {
+		t.Errorf("recorded %s", request)
+	}
+	if !strings.Contains(request.KeyConditionExpression, ">=") || request.FilterExpression == "" || len(request.ExpressionAttributeValues) != 3 {
+		t.Errorf("recorded expressions %s", request)
+	}
+}
+
+func TestExplainRecordsResults(t *testing.T) {
+	client := &fakeClient{queryOutputs: []*dynamodb.QueryOutput{
+		{Count: 0, ScannedCount: 4, LastEvaluatedKey: nil},
+	}}
+	explanation := &Explanation{}
+	if _, err := New(client).ListPlayersByGoalsThreshold(context.Background(), "USA", "WNT", 100, storage.PageRequest{PageLimit: 2, Explain: explanation}); err != nil {
+		t.Fatalf("ListPlayersByGoalsThreshold: %v", err)
+	}
+	if _, err := New(client).ListPlayersByGoalsThreshold(context.Background(), "USA", "WNT", 100, storage.PageRequest{PageLimit: 2}); err != nil {
+		t.Fatalf("ListPlayersByGoalsThreshold: %v", err)
+	}
+	requests := explanation.Requests()
+	if len(client.queries) != 2 || len(requests) != 1 {
+		t.Fatalf("sent %d queries and recorded %d, want 2 and 1", len(client.queries), len(requests))
+	}
+	if requests[0].ScannedCount != 4 || requests[0].Count != 0 {
+		t.Errorf("recorded %s, want 0 of 4 matched", requests[0])
+	}
+}
+
+type foreignExplainer struct{}
+
+func (foreignExplainer) String() string { return "foreign" }
+
+func TestExplainRejectsForeignExplainer(t *testing.T) {
+	client := &fakeClient{}
+	_, err := New(client).ListPlayersByGoalsThreshold(context.Background(), "USA", "WNT", 100, storage.PageRequest{PageLimit: 2, Explain: foreignExplainer{}})
+	if !errors.Is(err, storage.ErrExplainUnsupported) {
+		t.Errorf("got %v, want ErrExplainUnsupported", err)
+	}
+	if len(client.queries) != 0 {
+		t.Errorf("sent %d queries", len(client.queries))
+	}
+}
@@ -0,0 +1,14 @@
+package dynamo
+
+import (
+	"strconv"
+
+	"pagination/storage"
+)
+
+// ForgePageToken signs a ListPlayersByGoalsThreshold token with s's own key,
+// so the conformance suite can hand it cursors no request produces.
+func ForgePageToken(s storage.Storage, country string, nationalTeam string, goalThreshold int, pageLimit int32) (string, error) {
+	d := s.(*Dynamo)
+	return d.tokens.encode(&cursor{PageLimit: pageLimit}, queryBinding("ListPlayersByGoalsThreshold", d.table(), country, nationalTeam, strconv.Itoa(goalThreshold)))
+}
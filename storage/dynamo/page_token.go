@@ -0,0 +1,231 @@
+package dynamo
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"pagination/storage"
+)
+
+// pageTokenVersion is written into every new token. Version 2 is the only one
+// decoded: version 1 tokens were neither signed nor bound to their query, so
+// they are rejected. decode picks the decoder by version, which lets a future
+// format keep decodeV2 for the tokens still live across the upgrade.
+const (
+	pageTokenV2         = 2
+	pageTokenVersion    = pageTokenV2
+	defaultPageTokenTTL = 24 * time.Hour
+	pageTokenSeparator  = "."
+)
+
+// SigningKey is an HMAC key used to sign page tokens. The ID is embedded in
+// every token so that keys can be rotated without invalidating live tokens.
+type SigningKey struct {
+	ID     string
+	Secret []byte
+}
+
+// tokenHeader is the part of the payload every version shares: enough to
+// verify the signature and pick the decoder.
+type tokenHeader struct {
+	Version int    `json:"v"`
+	KeyID   string `json:"kid,omitempty"`
+}
+
+// pageToken is the payload of a version 2 token.
+type pageToken struct {
+	Version          int                      `json:"v"`
+	PageLimit        int32                    `json:"l"`
+	ScanIndexForward bool                     `json:"f,omitempty"`
+	IndexName        string                   `json:"i,omitempty"`
+	LastEvaluatedKey map[string]tokenKeyValue `json:"k,omitempty"`
+	Binding          string                   `json:"b,omitempty"` // hash of the query the token was issued for
+	ExpiresAt        int64                    `json:"e,omitempty"` // unix seconds, zero if the token never expires
+	KeyID            string                   `json:"kid,omitempty"`
+	Backward         bool                     `json:"r,omitempty"`
+}
+
+// cursor is the position a page token resumes from. LastEvaluatedKey is the
+// boundary item: a forward cursor continues after it, a Backward cursor
+// returns the page that ends just before it.
+type cursor struct {
+	PageLimit        int32
+	LastEvaluatedKey map[string]types.AttributeValue
+	ScanIndexForward bool
+	IndexName        string // empty for the base table
+	Backward         bool
+}
+
+// tokenKeyValue holds a single key attribute; key attributes can only be of
+// type S, N or B.
+type tokenKeyValue struct {
+	S *string `json:"s,omitempty"`
+	N *string `json:"n,omitempty"`
+	B []byte  `json:"b,omitempty"`
+}
+
+// pageTokenCodec turns cursors into opaque tokens and back. Tokens are signed
+// with the first key and verified against all of them, so a client can neither
+// forge a position nor lift the binding or expiry of a token it was given.
+type pageTokenCodec struct {
+	keys []SigningKey
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// queryBinding identifies the query a token belongs to, so a token issued for
+// one country, team or threshold cannot be replayed against another.
+func queryBinding(operation string, params ...string) string {
+	h := sha256.New()
+	for _, part := range append([]string{operation}, params...) {
+		h.Write([]byte(strconv.Itoa(len(part))))
+		h.Write([]byte(":"))
+		h.Write([]byte(part))
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func (c *pageTokenCodec) encode(cursor *cursor, binding string) (string, error) {
+	token := pageToken{
+		Version:          pageTokenVersion,
+		PageLimit:        cursor.PageLimit,
+		ScanIndexForward: cursor.ScanIndexForward,
+		IndexName:        cursor.IndexName,
+		Binding:          binding,
+		Backward:         cursor.Backward,
+	}
+	if c.ttl > 0 {
+		token.ExpiresAt = c.now().Add(c.ttl).Unix()
+	}
+	if cursor.LastEvaluatedKey != nil {
+		token.LastEvaluatedKey = make(map[string]tokenKeyValue, len(cursor.LastEvaluatedKey))
+		for name, av := range cursor.LastEvaluatedKey {
+			switch v := av.(type) {
+			case *types.AttributeValueMemberS:
+				token.LastEvaluatedKey[name] = tokenKeyValue{S: &v.Value}
+			case *types.AttributeValueMemberN:
+				token.LastEvaluatedKey[name] = tokenKeyValue{N: &v.Value}
+			case *types.AttributeValueMemberB:
+				token.LastEvaluatedKey[name] = tokenKeyValue{B: v.Value}
+			default:
+				return "", fmt.Errorf("unsupported key attribute type %T for %q", av, name)
+			}
+		}
+	}
+	if len(c.keys) == 0 {
+		return "", fmt.Errorf("no page token signing key configured")
+	}
+	token.KeyID = c.keys[0].ID
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + pageTokenSeparator + base64.RawURLEncoding.EncodeToString(sign(c.keys[0].Secret, encoded)), nil
+}
+
+func (c *pageTokenCodec) decode(encoded string, binding string) (*cursor, error) {
+	parts := strings.SplitN(encoded, pageTokenSeparator, 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%w: token is not signed", storage.ErrInvalidPageToken)
+	}
+	encodedPayload := parts[0]
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", storage.ErrInvalidPageToken, err)
+	}
+	var header tokenHeader
+	if err := json.Unmarshal(payload, &header); err != nil {
+		return nil, fmt.Errorf("%w: %v", storage.ErrInvalidPageToken, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", storage.ErrInvalidPageToken, err)
+	}
+	key, ok := c.key(header.KeyID)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signing key %q", storage.ErrInvalidPageToken, header.KeyID)
+	}
+	if !hmac.Equal(signature, sign(key.Secret, encodedPayload)) {
+		return nil, fmt.Errorf("%w: signature mismatch", storage.ErrInvalidPageToken)
+	}
+	switch header.Version {
+	case pageTokenV2:
+		return c.decodeV2(payload, binding)
+	default:
+		return nil, fmt.Errorf("%w: unsupported version %d", storage.ErrInvalidPageToken, header.Version)
+	}
+}
+
+// decodeV2 turns the verified payload of a version 2 token into a cursor.
+func (c *pageTokenCodec) decodeV2(payload []byte, binding string) (*cursor, error) {
+	var token pageToken
+	if err := json.Unmarshal(payload, &token); err != nil {
+		return nil, fmt.Errorf("%w: %v", storage.ErrInvalidPageToken, err)
+	}
+	if token.Binding != binding {
+		return nil, fmt.Errorf("%w: token was issued for a different query", storage.ErrInvalidPageToken)
+	}
+	if token.ExpiresAt != 0 && c.now().Unix() > token.ExpiresAt {
+		return nil, fmt.Errorf("%w: token expired", storage.ErrInvalidPageToken)
+	}
+	if token.PageLimit <= 0 {
+		return nil, fmt.Errorf("%w: page limit must be positive, got %d", storage.ErrInvalidPageToken, token.PageLimit)
+	}
+	cursor := &cursor{
+		PageLimit:        token.PageLimit,
+		ScanIndexForward: token.ScanIndexForward,
+		IndexName:        token.IndexName,
+		Backward:         token.Backward,
+	}
+	if token.LastEvaluatedKey != nil {
+		cursor.LastEvaluatedKey = make(map[string]types.AttributeValue, len(token.LastEvaluatedKey))
+		for name, v := range token.LastEvaluatedKey {
+			switch {
+			case v.S != nil:
+				cursor.LastEvaluatedKey[name] = &types.AttributeValueMemberS{Value: *v.S}
+			case v.N != nil:
+				cursor.LastEvaluatedKey[name] = &types.AttributeValueMemberN{Value: *v.N}
+			case v.B != nil:
+				cursor.LastEvaluatedKey[name] = &types.AttributeValueMemberB{Value: v.B}
+			default:
+				return nil, fmt.Errorf("%w: empty key attribute %q", storage.ErrInvalidPageToken, name)
+			}
+		}
+	}
+	return cursor, nil
+}
+
+func (c *pageTokenCodec) key(id string) (SigningKey, bool) {
+	for _, key := range c.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return SigningKey{}, false
+}
+
+// randomSigningKey returns a key for processes configured without
+// WithSigningKeys.
+func randomSigningKey() SigningKey {
+	secret := make([]byte, sha256.Size)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("generating page token signing key: %v", err))
+	}
+	return SigningKey{ID: "random", Secret: secret}
+}
+
+func sign(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
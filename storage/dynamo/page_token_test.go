@@ -0,0 +1,141 @@
+package dynamo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"pagination/storage"
+)
+
+var (
+	oldKey = SigningKey{ID: "k1", Secret: []byte("old secret")}
+	newKey = SigningKey{ID: "k2", Secret: []byte("new secret")}
+)
+
+func testCodec(now time.Time, keys ...SigningKey) *pageTokenCodec {
+	return &pageTokenCodec{keys: keys, ttl: time.Hour, now: func() time.Time { return now }}
+}
+
+func testCursor() *cursor {
+	return &cursor{PageLimit: 2, ScanIndexForward: true, LastEvaluatedKey: map[string]types.AttributeValue{
+		pk: &types.AttributeValueMemberS{Value: "USA"},
+		sk: &types.AttributeValueMemberS{Value: "WNT#Alex#Morgan"},
+	}}
+}
+
+func TestPageTokenRoundTrip(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	token, err := testCodec(now, oldKey).encode(testCursor(), "binding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := testCodec(now, oldKey).decode(token, "binding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.PageLimit != 2 || stringValue(cursor.LastEvaluatedKey[sk]) != "WNT#Alex#Morgan" {
+		t.Errorf("decoded %+v", cursor)
+	}
+}
+
+func TestPageTokenRejected(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	token, err := testCodec(now, oldKey).encode(testCursor(), "binding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.SplitN(token, pageTokenSeparator, 2)
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		t.Fatal(err)
+	}
+	fields["l"] = 1000
+	tampered, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields["l"], fields["v"] = 2, pageTokenVersion+1
+	future, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedFuture := base64.RawURLEncoding.EncodeToString(future)
+	signedFuture := encodedFuture + pageTokenSeparator + base64.RawURLEncoding.EncodeToString(sign(oldKey.Secret, encodedFuture))
+
+	tests := []struct {
+		name    string
+		codec   *pageTokenCodec
+		token   string
+		binding string
+	}{
+		{"tampered payload", testCodec(now, oldKey), base64.RawURLEncoding.EncodeToString(tampered) + pageTokenSeparator + parts[1], "binding"},
+		{"unknown key", testCodec(now, newKey), token, "binding"},
+		{"expired", testCodec(now.Add(time.Hour+time.Second), oldKey), token, "binding"},
+		{"unsigned", testCodec(now, oldKey), parts[0], "binding"},
+		{"other query", testCodec(now, oldKey), token, "other binding"},
+		{"unknown version", testCodec(now, oldKey), signedFuture, "binding"},
+	}
+	for _, tt := range tests {
+		if _, err := tt.codec.decode(tt.token, tt.binding); !errors.Is(err, storage.ErrInvalidPageToken) {
+			t.Errorf("%s: got %v, want ErrInvalidPageToken", tt.name, err)
+		}
+	}
+}
+
+func TestPageTokenKeyRotation(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	token, err := testCodec(now, oldKey).encode(testCursor(), "binding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated := testCodec(now, newKey, oldKey)
+	if _, err := rotated.decode(token, "binding"); err != nil {
+		t.Fatalf("token signed with the old key: %v", err)
+	}
+	token, err = rotated.encode(testCursor(), "binding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testCodec(now, newKey).decode(token, "binding"); err != nil {
+		t.Errorf("token signed after rotation is not signed with the new key: %v", err)
+	}
+}
+
+func TestPageTokenReplayedAgainstOtherQuery(t *testing.T) {
+	item := map[string]types.AttributeValue{
+		pk:    &types.AttributeValueMemberS{Value: "USA"},
+		sk:    &types.AttributeValueMemberS{Value: "WNT#Alex#Morgan"},
+		goals: &types.AttributeValueMemberN{Value: "119"},
+	}
+	client := &fakeClient{queryOutputs: []*dynamodb.QueryOutput{
+		{Items: []map[string]types.AttributeValue{item}, Count: 1, ScannedCount: 1, LastEvaluatedKey: item},
+	}}
+	d := New(client)
+	ctx := context.Background()
+	page, err := d.ListPlayersByGoalsThreshold(ctx, "USA", "WNT", 100, storage.PageRequest{PageLimit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.NextPageToken == "" {
+		t.Fatal("expected a next page token")
+	}
+	_, err = d.ListPlayersByGoalsThreshold(ctx, "Brazil", "WNT", 100, storage.PageRequest{PageLimit: 1, PageToken: page.NextPageToken})
+	if !errors.Is(err, storage.ErrInvalidPageToken) {
+		t.Errorf("token replayed for another country: got %v, want ErrInvalidPageToken", err)
+	}
+	if len(client.queries) != 1 {
+		t.Errorf("replayed token was sent, %d queries", len(client.queries))
+	}
+}
@@ -3,29 +3,63 @@ package dynamo
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"pagination/storage"
 )
 
 const (
 	pk                        = "pk"
 	sk                        = "sk"
-	playerStatsTable          = "player_stats_v1"
+	defaultTableName          = "player_stats_v1"
 	goals                     = "goals"
 	nationalTeamAttributeName = "national_team"
+	assistsAttributeName      = "assists"
+	appearancesAttributeName  = "appearances"
+	firstNameAttributeName    = "first_name"
 	identifierSeparator       = "#"
-	gsi                       = "GSI1"
+	defaultIndexName          = "GSI1"
+	defaultMaxBatchSize       = 1000
 )
 
 type playerStats struct {
-	dbClient *dynamodb.Client
+	dbClient    DynamoDBAPI
+	tokens      *pageTokenCodec
+	tablePrefix string
+	tableName   string
+	indexName   string
+	keys        keyAttributes
+}
+
+// keyAttributes are the stored names of the attributes statsItem marshals as
+// pk, sk and goals.
+type keyAttributes struct {
+	partitionKey string
+	sortKey      string
+	goals        string
 }
 
-type StatsRecord struct {
+func (k keyAttributes) byItemName() map[string]string {
+	return map[string]string{
+		pk:    k.partitionKey,
+		sk:    k.sortKey,
+		goals: k.goals,
+	}
+}
+
+func (p *playerStats) table() string {
+	return p.tablePrefix + p.tableName
+}
+
+// statsItem is the stored form of a storage.StatsRecord.
+type statsItem struct {
 	PartitionKey string `dynamodbav:"pk"`    // generic name, expected value is country
 	SortKey      string `dynamodbav:"sk"`    // generic name, expected value is <NationalTeam>#<FirstName>#<LastName>
 	Goals        int    `dynamodbav:"goals"` // sort key attribute for the gsi
@@ -37,17 +71,128 @@ type StatsRecord struct {
 	LastName     string `dynamodbav:"last_name"`
 }
 
+func (p *playerStats) newStatsItem(record *storage.StatsRecord) *statsItem {
+	return &statsItem{
+		PartitionKey: record.Country,
+		SortKey:      p.buildSortKey(record.NationalTeam, record.FirstName, record.LastName),
+		Goals:        record.Goals,
+		Assists:      record.Assists,
+		Appearances:  record.Appearances,
+		Country:      record.Country,
+		NationalTeam: record.NationalTeam,
+		FirstName:    record.FirstName,
+		LastName:     record.LastName,
+	}
+}
+
+func (i *statsItem) record() *storage.StatsRecord {
+	return &storage.StatsRecord{
+		Country:      i.Country,
+		NationalTeam: i.NationalTeam,
+		FirstName:    i.FirstName,
+		LastName:     i.LastName,
+		Goals:        i.Goals,
+		Assists:      i.Assists,
+		Appearances:  i.Appearances,
+	}
+}
+
+// toStored renames the key attributes of a marshalled statsItem to their
+// configured names.
+func (p *playerStats) toStored(av map[string]types.AttributeValue) map[string]types.AttributeValue {
+	renames := p.keys.byItemName()
+	stored := make(map[string]types.AttributeValue, len(av))
+	for name, value := range av {
+		if _, ok := renames[name]; !ok {
+			stored[name] = value
+		}
+	}
+	for itemName, storedName := range renames {
+		if value, ok := av[itemName]; ok {
+			stored[storedName] = value
+		}
+	}
+	return stored
+}
+
+// fromStored is the inverse of toStored.
+func (p *playerStats) fromStored(stored map[string]types.AttributeValue) map[string]types.AttributeValue {
+	av := make(map[string]types.AttributeValue, len(stored))
+	for name, value := range stored {
+		av[name] = value
+	}
+	for itemName, storedName := range p.keys.byItemName() {
+		if value, ok := stored[storedName]; ok {
+			av[itemName] = value
+		}
+	}
+	return av
+}
+
+func (p *playerStats) unmarshalRecords(items []map[string]types.AttributeValue) ([]*storage.StatsRecord, error) {
+	var statsItems []*statsItem
+	avs := make([]map[string]types.AttributeValue, 0, len(items))
+	for _, item := range items {
+		avs = append(avs, p.fromStored(item))
+	}
+	err := attributevalue.UnmarshalListOfMaps(avs, &statsItems)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*storage.StatsRecord, 0, len(statsItems))
+	for _, item := range statsItems {
+		records = append(records, item.record())
+	}
+	return records, nil
+}
+
+// projectionExpression reads the attributes of the selected fields plus the
+// key attributes page tokens are built from, adding its placeholders to names.
+func (p *playerStats) projectionExpression(fields storage.Fields, names map[string]string) (*string, map[string]string) {
+	attributes := []string{p.keys.partitionKey, p.keys.sortKey, p.keys.goals}
+	for _, field := range fields {
+		attribute := string(field) // fields are named after their attributes
+		if field == storage.FieldGoals {
+			attribute = p.keys.goals
+		}
+		attributes = append(attributes, attribute)
+	}
+	if names == nil {
+		names = make(map[string]string)
+	}
+	seen := make(map[string]bool, len(attributes))
+	var placeholders []string
+	for _, attribute := range attributes {
+		if seen[attribute] {
+			continue
+		}
+		seen[attribute] = true
+		placeholder := fmt.Sprintf("#proj%d", len(placeholders))
+		names[placeholder] = attribute
+		placeholders = append(placeholders, placeholder)
+	}
+	return aws.String(strings.Join(placeholders, ", ")), names
+}
+
+// projectRecords zeroes the fields a projected read did not select, including
+// the key attributes read for the page tokens.
+func projectRecords(records []*storage.StatsRecord, fields storage.Fields) {
+	for _, record := range records {
+		fields.Project(record)
+	}
+}
+
 func (p *playerStats) buildKey(country string, nationalTeam string, firstName string, lastName string) map[string]types.AttributeValue {
 	return map[string]types.AttributeValue{
-		pk: &types.AttributeValueMemberS{Value: country},
-		sk: &types.AttributeValueMemberS{Value: p.buildSortKey(nationalTeam, firstName, lastName)},
+		p.keys.partitionKey: &types.AttributeValueMemberS{Value: country},
+		p.keys.sortKey:      &types.AttributeValueMemberS{Value: p.buildSortKey(nationalTeam, firstName, lastName)},
 	}
 }
 
 func (p *playerStats) buildListPlayersQueryExpression(country string, nationalTeam string) (expression.Expression, error) {
 	var keyCond expression.KeyConditionBuilder
 	var builder expression.Builder
-	keyCond = expression.Key(pk).Equal(expression.Value(country)).And(expression.Key(sk).BeginsWith(fmt.Sprintf("%s", nationalTeam)))
+	keyCond = expression.Key(p.keys.partitionKey).Equal(expression.Value(country)).And(expression.Key(p.keys.sortKey).BeginsWith(fmt.Sprintf("%s", nationalTeam)))
 	builder = expression.NewBuilder().WithKeyCondition(keyCond)
 	expr, err := builder.Build()
 	return expr, err
@@ -57,87 +202,356 @@ func (p *playerStats) buildListPlayersWithGoalsFilterQueryExpression(country str
 	var keyCond expression.KeyConditionBuilder
 	var builder expression.Builder
 	var filter expression.ConditionBuilder
-	keyCond = expression.Key(pk).Equal(expression.Value(country)).And(expression.Key(sk).BeginsWith(fmt.Sprintf("%s", nationalTeam)))
-	filter = expression.Name(goals).GreaterThanEqual(expression.Value(goalThreshold))
+	keyCond = expression.Key(p.keys.partitionKey).Equal(expression.Value(country)).And(expression.Key(p.keys.sortKey).BeginsWith(fmt.Sprintf("%s", nationalTeam)))
+	filter = expression.Name(p.keys.goals).GreaterThanEqual(expression.Value(goalThreshold))
 	builder = expression.NewBuilder().WithKeyCondition(keyCond).WithFilter(filter)
 	expr, err := builder.Build()
 	return expr, err
 }
 
 func (p *playerStats) buildListPlayersWithGoalsSortedFilterQueryExpression(country string, nationalTeam string, goalThreshold int) (expression.Expression, error) {
-	var keyCond expression.KeyConditionBuilder
-	var builder expression.Builder
-	var filter expression.ConditionBuilder
-	keyCond = expression.Key(pk).Equal(expression.Value(country)).And(expression.Key(goals).GreaterThanEqual(expression.Value(goalThreshold)))
-	filter = expression.Name(nationalTeamAttributeName).Equal(expression.Value(nationalTeam))
-	builder = expression.NewBuilder().WithKeyCondition(keyCond).WithFilter(filter)
-	expr, err := builder.Build()
-	return expr, err
+	return p.buildGoalsRangeQueryExpression(country, nationalTeam, storage.AtLeast(goalThreshold))
+}
+
+// buildGoalsRangeQueryExpression queries the goals index with the range as key
+// condition; a nil range reads the whole partition.
+func (p *playerStats) buildGoalsRangeQueryExpression(country string, nationalTeam string, goalsRange *storage.Range) (expression.Expression, error) {
+	keyCond := p.goalsKeyCondition(country, goalsRange)
+	filter := expression.Name(nationalTeamAttributeName).Equal(expression.Value(nationalTeam))
+	return expression.NewBuilder().WithKeyCondition(keyCond).WithFilter(filter).Build()
+}
+
+// goalsKeyCondition is the key condition of a goals index query for the
+// players of country within goalsRange.
+func (p *playerStats) goalsKeyCondition(country string, goalsRange *storage.Range) expression.KeyConditionBuilder {
+	keyCond := expression.Key(p.keys.partitionKey).Equal(expression.Value(country))
+	goalsKey := expression.Key(p.keys.goals)
+	switch {
+	case goalsRange == nil:
+	case goalsRange.Min != nil && goalsRange.Max != nil && *goalsRange.Min == *goalsRange.Max:
+		keyCond = keyCond.And(goalsKey.Equal(expression.Value(*goalsRange.Min)))
+	case goalsRange.Min != nil && goalsRange.Max != nil:
+		keyCond = keyCond.And(goalsKey.Between(expression.Value(*goalsRange.Min), expression.Value(*goalsRange.Max)))
+	case goalsRange.Min != nil:
+		keyCond = keyCond.And(goalsKey.GreaterThanEqual(expression.Value(*goalsRange.Min)))
+	case goalsRange.Max != nil:
+		keyCond = keyCond.And(goalsKey.LessThanEqual(expression.Value(*goalsRange.Max)))
+	}
+	return keyCond
+}
+
+func (p *playerStats) buildScanInput(filter storage.ScanFilter, opts storage.ReadOptions) (*dynamodb.ScanInput, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	consistent, err := consistentRead(opts.ConsistentRead, "")
+	if err != nil {
+		return nil, err
+	}
+	scanTableInput := &dynamodb.ScanInput{
+		TableName:              aws.String(p.table()),
+		ConsistentRead:         consistent,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+	}
+	if filter.TotalSegments > 0 {
+		scanTableInput.Segment = aws.Int32(filter.Segment)
+		scanTableInput.TotalSegments = aws.Int32(filter.TotalSegments)
+	}
+	var conditions []expression.ConditionBuilder
+	if filter.NationalTeam != "" {
+		conditions = append(conditions, expression.Name(nationalTeamAttributeName).Equal(expression.Value(filter.NationalTeam)))
+	}
+	conditions = append(conditions, rangeConditions(p.keys.goals, filter.Goals)...)
+	conditions = append(conditions, rangeConditions(assistsAttributeName, filter.Assists)...)
+	conditions = append(conditions, rangeConditions(appearancesAttributeName, filter.Appearances)...)
+	if len(conditions) == 0 {
+		return scanTableInput, nil
+	}
+	expr, err := expression.NewBuilder().WithFilter(allOf(conditions)).Build()
+	if err != nil {
+		return nil, err
+	}
+	scanTableInput.ExpressionAttributeNames = expr.Names()
+	scanTableInput.ExpressionAttributeValues = expr.Values()
+	scanTableInput.FilterExpression = expr.Filter()
+	return scanTableInput, nil
+}
+
+// allOf joins one or more conditions with AND.
+func allOf(conditions []expression.ConditionBuilder) expression.ConditionBuilder {
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	return expression.And(conditions[0], conditions[1], conditions[2:]...)
+}
+
+// rangeConditions turns a range on the named attribute into filter conditions.
+func rangeConditions(name string, r *storage.Range) []expression.ConditionBuilder {
+	switch {
+	case r == nil:
+		return nil
+	case r.Min != nil && r.Max != nil:
+		return []expression.ConditionBuilder{expression.Name(name).Between(expression.Value(*r.Min), expression.Value(*r.Max))}
+	case r.Min != nil:
+		return []expression.ConditionBuilder{expression.Name(name).GreaterThanEqual(expression.Value(*r.Min))}
+	case r.Max != nil:
+		return []expression.ConditionBuilder{expression.Name(name).LessThanEqual(expression.Value(*r.Max))}
+	}
+	return nil
 }
 
 func (p *playerStats) buildExclusiveStartKey(lastEvaluatedItem map[string]types.AttributeValue) map[string]types.AttributeValue {
 	return map[string]types.AttributeValue{
-		pk: lastEvaluatedItem[pk],
-		sk: lastEvaluatedItem[sk],
+		p.keys.partitionKey: lastEvaluatedItem[p.keys.partitionKey],
+		p.keys.sortKey:      lastEvaluatedItem[p.keys.sortKey],
 	}
 }
 
 func (p *playerStats) buildExclusiveStartKeyForGSI(lastEvaluatedItem map[string]types.AttributeValue) map[string]types.AttributeValue {
 	return map[string]types.AttributeValue{
-		pk:    lastEvaluatedItem[pk],
-		sk:    lastEvaluatedItem[sk],
-		goals: lastEvaluatedItem[goals],
+		p.keys.partitionKey: lastEvaluatedItem[p.keys.partitionKey],
+		p.keys.sortKey:      lastEvaluatedItem[p.keys.sortKey],
+		p.keys.goals:        lastEvaluatedItem[p.keys.goals],
+	}
+}
+
+// pageRead is a paginated read: the cursor it resumes from and the options of
+// the request, which are not carried in page tokens.
+type pageRead struct {
+	*cursor
+	batchSize       int32
+	maxBatchSize    int32
+	maxScanned      int32
+	maxReadCapacity float64
+	lookAhead       bool
+	fields          storage.Fields
+	consistentRead  bool
+	client          DynamoDBAPI
+}
+
+func (p *playerStats) readForPage(page storage.PageRequest, indexName string, binding string) (*pageRead, error) {
+	if page.BatchSize < 0 || page.MaxBatchSize < 0 {
+		return nil, fmt.Errorf("batch sizes must not be negative, got %d and %d", page.BatchSize, page.MaxBatchSize)
+	}
+	if err := page.Fields.Validate(); err != nil {
+		return nil, err
+	}
+	if page.MaxScanned < 0 || page.MaxReadCapacity < 0 {
+		return nil, fmt.Errorf("read budgets must not be negative, got %d items and %g capacity units", page.MaxScanned, page.MaxReadCapacity)
+	}
+	if page.PageToken == "" && page.PageLimit <= 0 {
+		return nil, fmt.Errorf("page limit must be positive, got %d", page.PageLimit)
+	}
+	c := &cursor{PageLimit: page.PageLimit, ScanIndexForward: page.ScanIndexForward, IndexName: indexName}
+	if page.PageToken != "" {
+		var err error
+		if c, err = p.tokens.decode(page.PageToken, binding); err != nil {
+			return nil, err
+		}
+		if c.IndexName != indexName {
+			return nil, fmt.Errorf("%w: token was issued for index %q", storage.ErrInvalidPageToken, c.IndexName)
+		}
+	}
+	client, err := p.client(page.Explain)
+	if err != nil {
+		return nil, err
+	}
+	read := &pageRead{
+		cursor:          c,
+		batchSize:       page.BatchSize,
+		maxBatchSize:    page.MaxBatchSize,
+		maxScanned:      page.MaxScanned,
+		maxReadCapacity: page.MaxReadCapacity,
+		lookAhead:       page.LookAhead,
+		fields:          page.Fields,
+		consistentRead:  page.ConsistentRead,
+		client:          client,
+	}
+	if read.batchSize == 0 {
+		read.batchSize = read.PageLimit
+		if read.lookAhead {
+			read.batchSize++
+		}
+	}
+	if read.maxBatchSize == 0 {
+		read.maxBatchSize = defaultMaxBatchSize
+	}
+	return read, nil
+}
+
+// nextBatchSize sizes the next read of a page that still needs pending items,
+// given that the last read of size current matched count of scanned items. It
+// asks for enough items to fill the page at the observed hit ratio, doubling
+// when nothing matched, and never shrinks the batch or exceeds max.
+func nextBatchSize(current int32, max int32, pending int32, count int32, scanned int32) int32 {
+	if current >= max || scanned == 0 {
+		return current
+	}
+	want := 2 * int64(current)
+	if count > 0 {
+		want = (int64(pending)*int64(scanned) + int64(count) - 1) / int64(count)
+	}
+	if want > int64(max) {
+		return max
+	}
+	if want < int64(current) {
+		return current
 	}
+	return int32(want)
+}
+
+// consistentRead is the ConsistentRead setting for a read of indexName that
+// asks for consistent reads or not. Global secondary indexes only serve
+// eventually consistent reads.
+func consistentRead(consistent bool, indexName string) (*bool, error) {
+	if !consistent {
+		return nil, nil
+	}
+	if indexName != "" {
+		return nil, fmt.Errorf("%w: %s", storage.ErrConsistentReadOnIndex, indexName)
+	}
+	return aws.Bool(true), nil
+}
+
+func (p *playerStats) nextPageToken(c *cursor, binding string) (string, error) {
+	if c.LastEvaluatedKey == nil {
+		return "", nil
+	}
+	return p.tokens.encode(c, binding)
 }
 
 func (p *playerStats) buildSortKey(nationalTeam string, firstName string, lastName string) string {
 	return fmt.Sprintf("%s%s%s%s%s", nationalTeam, identifierSeparator, firstName, identifierSeparator, lastName)
 }
 
-func (p *playerStats) ScanStatsTable(ctx context.Context, cursor *Cursor) ([]*StatsRecord, error) {
-	var records []*StatsRecord
-	scanTableInput := &dynamodb.ScanInput{
-		TableName: aws.String(playerStatsTable),
-		Limit:     aws.Int32(cursor.PageLimit),
-	}
-	resp, err := p.dbClient.Scan(ctx, scanTableInput)
+func (p *playerStats) ScanStatsTable(ctx context.Context, filter storage.ScanFilter, page storage.PageRequest) (*storage.Page, error) {
+	var collectiveResult []map[string]types.AttributeValue
+	binding := queryBinding("ScanStatsTable", p.table(), filter.String())
+	read, err := p.readForPage(page, "", binding)
 	if err != nil {
 		return nil, err
 	}
-	err = attributevalue.UnmarshalListOfMaps(resp.Items, &records)
+	scanTableInput, err := p.buildScanInput(filter, storage.ReadOptions{ConsistentRead: read.consistentRead})
 	if err != nil {
 		return nil, err
 	}
-	return records, nil
+	scanTableInput.Limit = aws.Int32(read.PageLimit)
+	if read.LastEvaluatedKey != nil {
+		scanTableInput.ExclusiveStartKey = read.LastEvaluatedKey
+	}
+	if read.fields != nil {
+		scanTableInput.ProjectionExpression, scanTableInput.ExpressionAttributeNames = p.projectionExpression(read.fields, scanTableInput.ExpressionAttributeNames)
+	}
+	result := &storage.Page{Fields: read.fields}
+	paginator := dynamodb.NewScanPaginator(read.client, scanTableInput)
+	for {
+		if !paginator.HasMorePages() {
+			read.LastEvaluatedKey = nil
+			break
+		}
+		singlePage, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		result.ScannedCount += singlePage.ScannedCount
+		result.ConsumedCapacity = addConsumedCapacity(result.ConsumedCapacity, singlePage.ConsumedCapacity)
+		pendingItems := int(read.PageLimit) - len(collectiveResult)
+		if int(singlePage.Count) >= pendingItems {
+			collectiveResult = append(collectiveResult, singlePage.Items[:pendingItems]...)
+			read.LastEvaluatedKey = nil
+			if singlePage.LastEvaluatedKey != nil || int(singlePage.Count) > pendingItems {
+				read.LastEvaluatedKey = p.buildExclusiveStartKey(singlePage.Items[pendingItems-1])
+			}
+			break
+		}
+		collectiveResult = append(collectiveResult, singlePage.Items...)
+	}
+	if result.Records, err = p.unmarshalRecords(collectiveResult); err != nil {
+		return nil, err
+	}
+	projectRecords(result.Records, read.fields)
+	if result.NextPageToken, err = p.nextPageToken(read.cursor, binding); err != nil {
+		return nil, err
+	}
+	result.Count = int32(len(result.Records))
+	result.HasMore = result.NextPageToken != ""
+	return result, nil
 }
 
-func (p *playerStats) GetPlayerStats(ctx context.Context, country string, nationalTeam string, firstName string, lastName string) (*StatsRecord, error) {
-	var playerRecord *StatsRecord
-	resp, err := p.dbClient.GetItem(ctx, &dynamodb.GetItemInput{
-		Key:       p.buildKey(country, nationalTeam, firstName, lastName),
-		TableName: aws.String(playerStatsTable),
+// ScanAllStats walks the whole table, handing each page DynamoDB returns to fn
+// as it arrives. Returning an error from fn stops the scan.
+func (p *playerStats) ScanAllStats(ctx context.Context, filter storage.ScanFilter, opts storage.ReadOptions, fn func(page *storage.Page) error) error {
+	scanTableInput, err := p.buildScanInput(filter, opts)
+	if err != nil {
+		return err
+	}
+	client, err := p.client(opts.Explain)
+	if err != nil {
+		return err
+	}
+	paginator := dynamodb.NewScanPaginator(client, scanTableInput)
+	for paginator.HasMorePages() {
+		singlePage, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		records, err := p.unmarshalRecords(singlePage.Items)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			continue
+		}
+		page := &storage.Page{
+			Records:          records,
+			HasMore:          paginator.HasMorePages(),
+			Count:            singlePage.Count,
+			ScannedCount:     singlePage.ScannedCount,
+			ConsumedCapacity: addConsumedCapacity(nil, singlePage.ConsumedCapacity),
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *playerStats) GetPlayerStats(ctx context.Context, country string, nationalTeam string, firstName string, lastName string, opts storage.ReadOptions) (*storage.StatsRecord, error) {
+	var playerRecord *statsItem
+	consistent, err := consistentRead(opts.ConsistentRead, "")
+	if err != nil {
+		return nil, err
+	}
+	client, err := p.client(opts.Explain)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		ConsistentRead: consistent,
+		Key:            p.buildKey(country, nationalTeam, firstName, lastName),
+		TableName:      aws.String(p.table()),
 	})
 	if err != nil {
 		return nil, err
 	}
 	if len(resp.Item) == 0 {
-		return nil, fmt.Errorf("record not found")
+		return nil, storage.ErrNotFound
 	}
-	err = attributevalue.UnmarshalMap(resp.Item, &playerRecord)
+	err = attributevalue.UnmarshalMap(p.fromStored(resp.Item), &playerRecord)
 	if err != nil {
 		return nil, err
 	}
-	return playerRecord, nil
+	return playerRecord.record(), nil
 }
 
-func (p *playerStats) PutPlayerStats(ctx context.Context, playerRecord *StatsRecord) error {
-	av, err := attributevalue.MarshalMap(playerRecord)
+func (p *playerStats) PutPlayerStats(ctx context.Context, playerRecord *storage.StatsRecord) error {
+	av, err := attributevalue.MarshalMap(p.newStatsItem(playerRecord))
 	if err != nil {
 		return err
 	}
 	putItemInput := &dynamodb.PutItemInput{
-		Item:      av,
-		TableName: aws.String(playerStatsTable),
+		Item:      p.toStored(av),
+		TableName: aws.String(p.table()),
 	}
 	_, err = p.dbClient.PutItem(ctx, putItemInput)
 	if err != nil {
@@ -146,43 +560,59 @@ func (p *playerStats) PutPlayerStats(ctx context.Context, playerRecord *StatsRec
 	return nil
 }
 
-func (p *playerStats) ListPlayers(ctx context.Context, country string, nationalTeam string) ([]*StatsRecord, error) {
-	var records []*StatsRecord
+func (p *playerStats) ListPlayers(ctx context.Context, country string, nationalTeam string, opts storage.ReadOptions) ([]*storage.StatsRecord, error) {
 	expr, err := p.buildListPlayersQueryExpression(country, nationalTeam)
 	if err != nil {
 		return nil, err
 	}
+	consistent, err := consistentRead(opts.ConsistentRead, "")
+	if err != nil {
+		return nil, err
+	}
+	client, err := p.client(opts.Explain)
+	if err != nil {
+		return nil, err
+	}
 	queryInput := &dynamodb.QueryInput{
+		ConsistentRead:            consistent,
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		KeyConditionExpression:    expr.KeyCondition(),
-		TableName:                 aws.String(playerStatsTable),
+		TableName:                 aws.String(p.table()),
 	}
-	resp, err := p.dbClient.Query(ctx, queryInput)
+	resp, err := client.Query(ctx, queryInput)
 	if err != nil {
 		return nil, err
 	}
-	err = attributevalue.UnmarshalListOfMaps(resp.Items, &records)
+	records, err := p.unmarshalRecords(resp.Items)
 	if err != nil {
 		return nil, err
 	}
 	return records, nil
 }
 
-func (p *playerStats) ListAllPlayers(ctx context.Context, country string, nationalTeam string) ([]*StatsRecord, error) {
+func (p *playerStats) ListAllPlayers(ctx context.Context, country string, nationalTeam string, opts storage.ReadOptions) ([]*storage.StatsRecord, error) {
 	var collectiveResult []map[string]types.AttributeValue
-	var records []*StatsRecord
 	expr, err := p.buildListPlayersQueryExpression(country, nationalTeam)
 	if err != nil {
 		return nil, err
 	}
+	consistent, err := consistentRead(opts.ConsistentRead, "")
+	if err != nil {
+		return nil, err
+	}
+	client, err := p.client(opts.Explain)
+	if err != nil {
+		return nil, err
+	}
 	queryInput := &dynamodb.QueryInput{
+		ConsistentRead:            consistent,
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		KeyConditionExpression:    expr.KeyCondition(),
-		TableName:                 aws.String(playerStatsTable),
+		TableName:                 aws.String(p.table()),
 	}
-	paginator := dynamodb.NewQueryPaginator(p.dbClient, queryInput)
+	paginator := dynamodb.NewQueryPaginator(client, queryInput)
 	for {
 		if !paginator.HasMorePages() {
 			break
@@ -193,44 +623,85 @@ func (p *playerStats) ListAllPlayers(ctx context.Context, country string, nation
 		}
 		collectiveResult = append(collectiveResult, singlePage.Items...)
 	}
-	err = attributevalue.UnmarshalListOfMaps(collectiveResult, &records)
+	records, err := p.unmarshalRecords(collectiveResult)
 	if err != nil {
 		return nil, err
 	}
 	return records, nil
 }
 
-func (p *playerStats) ListLimitedPlayers(ctx context.Context, country string, nationalTeam string, cursor *Cursor) ([]*StatsRecord, error) {
-	var records []*StatsRecord
+// StreamAllPlayers hands records to fn as each page of the query arrives. The
+// next page is only requested once fn has consumed the current one, so at most
+// one page is held in memory.
+func (p *playerStats) StreamAllPlayers(ctx context.Context, country string, nationalTeam string, opts storage.ReadOptions, fn func(record *storage.StatsRecord) error) error {
 	expr, err := p.buildListPlayersQueryExpression(country, nationalTeam)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	consistent, err := consistentRead(opts.ConsistentRead, "")
+	if err != nil {
+		return err
+	}
+	client, err := p.client(opts.Explain)
+	if err != nil {
+		return err
 	}
 	queryInput := &dynamodb.QueryInput{
+		ConsistentRead:            consistent,
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		KeyConditionExpression:    expr.KeyCondition(),
-		TableName:                 aws.String(playerStatsTable),
-		Limit:                     aws.Int32(cursor.PageLimit),
+		TableName:                 aws.String(p.table()),
 	}
-	if cursor.LastEvaluatedKey != nil {
-		queryInput.ExclusiveStartKey = cursor.LastEvaluatedKey
+	paginator := dynamodb.NewQueryPaginator(client, queryInput)
+	for paginator.HasMorePages() {
+		singlePage, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		records, err := p.unmarshalRecords(singlePage.Items)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
 	}
-	resp, err := p.dbClient.Query(ctx, queryInput)
+	return nil
+}
+
+func (p *playerStats) ListLimitedPlayers(ctx context.Context, country string, nationalTeam string, page storage.PageRequest) (*storage.Page, error) {
+	// results are always in ascending sort key order
+	page.ScanIndexForward = true
+	binding := queryBinding("ListLimitedPlayers", p.table(), country, nationalTeam)
+	read, err := p.readForPage(page, "", binding)
 	if err != nil {
 		return nil, err
 	}
-	cursor.LastEvaluatedKey = resp.LastEvaluatedKey
-	err = attributevalue.UnmarshalListOfMaps(resp.Items, &records)
+	expr, err := p.buildListPlayersQueryExpression(country, nationalTeam)
 	if err != nil {
 		return nil, err
 	}
-	return records, nil
+	queryInput := &dynamodb.QueryInput{
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		TableName:                 aws.String(p.table()),
+	}
+	return p.queryPage(ctx, queryInput, read, p.buildExclusiveStartKey, binding)
 }
 
-func (p *playerStats) ListPlayersByGoalsThreshold(ctx context.Context, country string, nationalTeam string, goalThreshold int, cursor *Cursor) ([]*StatsRecord, error) {
-	var collectiveResult []map[string]types.AttributeValue
-	var records []*StatsRecord
+func (p *playerStats) ListPlayersByGoalsThreshold(ctx context.Context, country string, nationalTeam string, goalThreshold int, page storage.PageRequest) (*storage.Page, error) {
+	binding := queryBinding("ListPlayersByGoalsThreshold", p.table(), country, nationalTeam, strconv.Itoa(goalThreshold))
+	read, err := p.readForPage(page, "", binding)
+	if err != nil {
+		return nil, err
+	}
 	expr, err := p.buildListPlayersWithGoalsFilterQueryExpression(country, nationalTeam, goalThreshold)
 	if err != nil {
 		return nil, err
@@ -240,43 +711,44 @@ func (p *playerStats) ListPlayersByGoalsThreshold(ctx context.Context, country s
 		ExpressionAttributeValues: expr.Values(),
 		KeyConditionExpression:    expr.KeyCondition(),
 		FilterExpression:          expr.Filter(),
-		TableName:                 aws.String(playerStatsTable),
-		Limit:                     aws.Int32(cursor.PageLimit),
-		ScanIndexForward:          aws.Bool(cursor.ScanIndexForward),
+		TableName:                 aws.String(p.table()),
 	}
-	if cursor.LastEvaluatedKey != nil {
-		queryInput.ExclusiveStartKey = cursor.LastEvaluatedKey
-	}
-	paginator := dynamodb.NewQueryPaginator(p.dbClient, queryInput)
-	for {
-		if !paginator.HasMorePages() {
-			fmt.Println("no more records in the partition")
-			cursor.LastEvaluatedKey = nil
-			break
-		}
-		singlePage, err := paginator.NextPage(ctx)
-		if err != nil {
-			return nil, err
-		}
-		pendingItems := int(cursor.PageLimit) - len(collectiveResult)
-		if int(singlePage.Count) >= pendingItems {
-			collectiveResult = append(collectiveResult, singlePage.Items[:pendingItems]...)
-			cursor.LastEvaluatedKey = p.buildExclusiveStartKey(singlePage.Items[pendingItems-1])
-			break
-		}
-		collectiveResult = append(collectiveResult, singlePage.Items...)
+	return p.queryPage(ctx, queryInput, read, p.buildExclusiveStartKey, binding)
+}
+
+func (p *playerStats) ListPlayersByGoalsThresholdSorted(ctx context.Context, country string, nationalTeam string, goalThreshold int, page storage.PageRequest) (*storage.Page, error) {
+	binding := queryBinding("ListPlayersByGoalsThresholdSorted", p.table(), country, nationalTeam, strconv.Itoa(goalThreshold), p.indexName)
+	read, err := p.readForPage(page, p.indexName, binding)
+	if err != nil {
+		return nil, err
 	}
-	err = attributevalue.UnmarshalListOfMaps(collectiveResult, &records)
+	expr, err := p.buildListPlayersWithGoalsSortedFilterQueryExpression(country, nationalTeam, goalThreshold)
 	if err != nil {
 		return nil, err
 	}
-	return records, nil
+	queryInput := &dynamodb.QueryInput{
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		IndexName:                 aws.String(p.indexName),
+		TableName:                 aws.String(p.table()),
+	}
+	return p.queryPage(ctx, queryInput, read, p.buildExclusiveStartKeyForGSI, binding)
 }
 
-func (p *playerStats) ListPlayersByGoalsThresholdSorted(ctx context.Context, country string, nationalTeam string, goalThreshold int, cursor *Cursor) ([]*StatsRecord, error) {
-	var collectiveResult []map[string]types.AttributeValue
-	var records []*StatsRecord
-	expr, err := p.buildListPlayersWithGoalsSortedFilterQueryExpression(country, nationalTeam, goalThreshold)
+// ListPlayersByGoalsRange pages through a national team's players whose goals
+// lie within goalsRange, ordered by goals on the goals index.
+func (p *playerStats) ListPlayersByGoalsRange(ctx context.Context, country string, nationalTeam string, goalsRange *storage.Range, page storage.PageRequest) (*storage.Page, error) {
+	if err := goalsRange.Validate(); err != nil {
+		return nil, err
+	}
+	binding := queryBinding("ListPlayersByGoalsRange", p.table(), country, nationalTeam, goalsRange.String(), p.indexName)
+	read, err := p.readForPage(page, p.indexName, binding)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := p.buildGoalsRangeQueryExpression(country, nationalTeam, goalsRange)
 	if err != nil {
 		return nil, err
 	}
@@ -285,35 +757,160 @@ func (p *playerStats) ListPlayersByGoalsThresholdSorted(ctx context.Context, cou
 		ExpressionAttributeValues: expr.Values(),
 		KeyConditionExpression:    expr.KeyCondition(),
 		FilterExpression:          expr.Filter(),
-		IndexName:                 aws.String(gsi),
-		TableName:                 aws.String(playerStatsTable),
-		Limit:                     aws.Int32(cursor.PageLimit),
-		ScanIndexForward:          aws.Bool(cursor.ScanIndexForward),
+		IndexName:                 aws.String(p.indexName),
+		TableName:                 aws.String(p.table()),
 	}
-	if cursor.LastEvaluatedKey != nil {
-		queryInput.ExclusiveStartKey = cursor.LastEvaluatedKey
+	return p.queryPage(ctx, queryInput, read, p.buildExclusiveStartKeyForGSI, binding)
+}
+
+// queryPage runs queryInput from the cursor position until read.PageLimit
+// items matched, plus one with read.lookAhead, the results ran out or the
+// request's read budget is spent. It reads read.batchSize items per request
+// and grows the batch when the filter rejects most of them. A backward cursor
+// queries in the opposite direction and reverses the items, so pages always
+// read in the requested order.
+func (p *playerStats) queryPage(ctx context.Context, queryInput *dynamodb.QueryInput, read *pageRead, startKey func(map[string]types.AttributeValue) map[string]types.AttributeValue, binding string) (*storage.Page, error) {
+	var collectiveResult []map[string]types.AttributeValue
+	consistent, err := consistentRead(read.consistentRead, aws.ToString(queryInput.IndexName))
+	if err != nil {
+		return nil, err
 	}
-	paginator := dynamodb.NewQueryPaginator(p.dbClient, queryInput)
+	queryInput.ConsistentRead = consistent
+	queryInput.ScanIndexForward = aws.Bool(read.ScanIndexForward != read.Backward)
+	if read.LastEvaluatedKey != nil {
+		queryInput.ExclusiveStartKey = read.LastEvaluatedKey
+	}
+	queryInput.ReturnConsumedCapacity = types.ReturnConsumedCapacityIndexes
+	if read.fields != nil {
+		queryInput.ProjectionExpression, queryInput.ExpressionAttributeNames = p.projectionExpression(read.fields, queryInput.ExpressionAttributeNames)
+	}
+	// resumeKey is where the query stopped, nil if it ran out of results
+	var resumeKey map[string]types.AttributeValue
+	budgetExhausted := false
+	var scanned int32
+	var consumed *storage.ConsumedCapacity
+	target := int(read.PageLimit)
+	if read.lookAhead {
+		target++
+	}
+	// not a paginator, as the batch size changes between requests
+	batchSize := read.batchSize
 	for {
-		if !paginator.HasMorePages() {
-			cursor.LastEvaluatedKey = nil
-			break
+		limit := batchSize
+		if read.maxScanned > 0 && read.maxScanned-scanned < limit {
+			limit = read.maxScanned - scanned
 		}
-		singlePage, err := paginator.NextPage(ctx)
+		queryInput.Limit = aws.Int32(limit)
+		singlePage, err := read.client.Query(ctx, queryInput)
 		if err != nil {
 			return nil, err
 		}
-		pendingItems := int(cursor.PageLimit) - len(collectiveResult)
+		scanned += singlePage.ScannedCount
+		consumed = addConsumedCapacity(consumed, singlePage.ConsumedCapacity)
+		pendingItems := target - len(collectiveResult)
 		if int(singlePage.Count) >= pendingItems {
 			collectiveResult = append(collectiveResult, singlePage.Items[:pendingItems]...)
-			cursor.LastEvaluatedKey = p.buildExclusiveStartKeyForGSI(singlePage.Items[pendingItems-1])
+			// the look-ahead item only proves that more follow
+			collectiveResult = collectiveResult[:read.PageLimit]
+			// a response that ends the results and holds no more than the
+			// page leaves nothing to resume
+			if singlePage.LastEvaluatedKey != nil || int(singlePage.Count) > pendingItems {
+				resumeKey = startKey(collectiveResult[len(collectiveResult)-1])
+			}
 			break
 		}
 		collectiveResult = append(collectiveResult, singlePage.Items...)
+		if singlePage.LastEvaluatedKey == nil {
+			break
+		}
+		if read.maxScanned > 0 && scanned >= read.maxScanned || read.maxReadCapacity > 0 && consumed != nil && consumed.CapacityUnits >= read.maxReadCapacity {
+			// resume after the last item read rather than the last match, so
+			// the next call does not evaluate the rejected items again
+			resumeKey = singlePage.LastEvaluatedKey
+			budgetExhausted = true
+			break
+		}
+		queryInput.ExclusiveStartKey = singlePage.LastEvaluatedKey
+		batchSize = nextBatchSize(batchSize, read.maxBatchSize, int32(pendingItems)-singlePage.Count, singlePage.Count, singlePage.ScannedCount)
 	}
-	err = attributevalue.UnmarshalListOfMaps(collectiveResult, &records)
+	if read.Backward {
+		reverseItems(collectiveResult)
+	}
+	records, err := p.unmarshalRecords(collectiveResult)
 	if err != nil {
 		return nil, err
 	}
-	return records, nil
+	projectRecords(records, read.fields)
+	page := &storage.Page{
+		Records:          records,
+		Fields:           read.fields,
+		BudgetExhausted:  budgetExhausted,
+		Count:            int32(len(records)),
+		ScannedCount:     scanned,
+		ConsumedCapacity: consumed,
+	}
+	if len(collectiveResult) == 0 && !budgetExhausted {
+		return page, nil
+	}
+
+	// the page is bounded by resumeKey in the direction of the query and by
+	// its first item, or the cursor position for an empty page, in the other
+	ahead, behind := resumeKey, read.LastEvaluatedKey
+	if len(collectiveResult) > 0 {
+		edge := collectiveResult[0]
+		if read.Backward {
+			edge = collectiveResult[len(collectiveResult)-1]
+		}
+		behind = startKey(edge)
+	}
+	nextKey, prevKey := ahead, behind
+	if read.Backward {
+		nextKey, prevKey = behind, ahead
+	}
+	// the first page has nothing before it
+	if !read.Backward && read.LastEvaluatedKey == nil {
+		prevKey = nil
+	}
+	if nextKey != nil {
+		next := &cursor{PageLimit: read.PageLimit, ScanIndexForward: read.ScanIndexForward, IndexName: read.IndexName, LastEvaluatedKey: nextKey}
+		if page.NextPageToken, err = p.tokens.encode(next, binding); err != nil {
+			return nil, err
+		}
+		page.HasMore = true
+	}
+	if prevKey != nil {
+		prev := &cursor{PageLimit: read.PageLimit, ScanIndexForward: read.ScanIndexForward, IndexName: read.IndexName, LastEvaluatedKey: prevKey, Backward: true}
+		if page.PrevPageToken, err = p.tokens.encode(prev, binding); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// addConsumedCapacity adds the capacity a response consumed to total, which
+// may be nil before the first response that reported any.
+func addConsumedCapacity(total *storage.ConsumedCapacity, consumed *types.ConsumedCapacity) *storage.ConsumedCapacity {
+	if consumed == nil {
+		return total
+	}
+	if total == nil {
+		total = &storage.ConsumedCapacity{}
+	}
+	total.CapacityUnits += aws.ToFloat64(consumed.CapacityUnits)
+	if consumed.Table != nil {
+		total.Table += aws.ToFloat64(consumed.Table.CapacityUnits)
+	}
+	for name, capacity := range consumed.GlobalSecondaryIndexes {
+		if total.Indexes == nil {
+			total.Indexes = make(map[string]float64)
+		}
+		total.Indexes[name] += aws.ToFloat64(capacity.CapacityUnits)
+	}
+	return total
+}
+
+func reverseItems(items []map[string]types.AttributeValue) {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
 }
@@ -0,0 +1,510 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"pagination/storage"
+)
+
+// fakeClient records requests and replays canned responses. Calls without a
+// canned response panic through the nil embedded interface.
+type fakeClient struct {
+	DynamoDBAPI
+	queries       []*dynamodb.QueryInput
+	queryOutputs  []*dynamodb.QueryOutput
+	scans         []*dynamodb.ScanInput
+	scanOutputs   []*dynamodb.ScanOutput
+	puts          []*dynamodb.PutItemInput
+	gets          []*dynamodb.GetItemInput
+	getItemOutput *dynamodb.GetItemOutput
+}
+
+func (f *fakeClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	// copy, as the caller reuses params across requests
+	query := *params
+	f.queries = append(f.queries, &query)
+	if len(f.queryOutputs) == 0 {
+		return &dynamodb.QueryOutput{}, nil
+	}
+	out := f.queryOutputs[0]
+	f.queryOutputs = f.queryOutputs[1:]
+	return out, nil
+}
+
+func (f *fakeClient) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	scan := *params
+	f.scans = append(f.scans, &scan)
+	if len(f.scanOutputs) == 0 {
+		return &dynamodb.ScanOutput{}, nil
+	}
+	out := f.scanOutputs[0]
+	f.scanOutputs = f.scanOutputs[1:]
+	return out, nil
+}
+
+func (f *fakeClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.puts = append(f.puts, params)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	f.gets = append(f.gets, params)
+	return f.getItemOutput, nil
+}
+
+func stringValue(av types.AttributeValue) string {
+	if s, ok := av.(*types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
+
+func TestPutPlayerStatsBuildsKeys(t *testing.T) {
+	client := &fakeClient{}
+	err := New(client).PutPlayerStats(context.Background(), &storage.StatsRecord{
+		Country:      "USA",
+		NationalTeam: "WNT",
+		FirstName:    "Alex",
+		LastName:     "Morgan",
+		Goals:        119,
+	})
+	if err != nil {
+		t.Fatalf("PutPlayerStats: %v", err)
+	}
+	item := client.puts[0].Item
+	if got := stringValue(item[pk]); got != "USA" {
+		t.Errorf("pk = %q, want %q", got, "USA")
+	}
+	if got := stringValue(item[sk]); got != "WNT#Alex#Morgan" {
+		t.Errorf("sk = %q, want %q", got, "WNT#Alex#Morgan")
+	}
+}
+
+func TestGetPlayerStatsNotFound(t *testing.T) {
+	client := &fakeClient{getItemOutput: &dynamodb.GetItemOutput{}}
+	_, err := New(client).GetPlayerStats(context.Background(), "USA", "WNT", "No", "One", storage.ReadOptions{})
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+}
+
+func TestConsistentReadOnTableOnly(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeClient{getItemOutput: &dynamodb.GetItemOutput{}}
+	d := New(client)
+	if _, err := d.GetPlayerStats(ctx, "USA", "WNT", "Alex", "Morgan", storage.ReadOptions{ConsistentRead: true}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetPlayerStats: %v", err)
+	}
+	if _, err := d.ListLimitedPlayers(ctx, "USA", "WNT", storage.PageRequest{PageLimit: 2, ConsistentRead: true}); err != nil {
+		t.Fatalf("ListLimitedPlayers: %v", err)
+	}
+	if !aws.ToBool(client.gets[0].ConsistentRead) || !aws.ToBool(client.queries[0].ConsistentRead) {
+		t.Errorf("ConsistentRead not set on base table reads")
+	}
+
+	_, err := d.ListPlayersByGoalsRange(ctx, "USA", "WNT", storage.AtLeast(100), storage.PageRequest{PageLimit: 2, ConsistentRead: true})
+	if !errors.Is(err, storage.ErrConsistentReadOnIndex) {
+		t.Errorf("ListPlayersByGoalsRange: got %v, want ErrConsistentReadOnIndex", err)
+	}
+	if _, err := d.CountPlayersByGoalsThreshold(ctx, "USA", "WNT", 100, storage.CountOptions{ConsistentRead: true}); err != nil {
+		t.Fatalf("CountPlayersByGoalsThreshold: %v", err)
+	}
+	if len(client.queries) != 2 || client.queries[1].IndexName != nil {
+		t.Errorf("consistent count should query the table, sent %d queries", len(client.queries))
+	}
+}
+
+func TestPageLimitMustBePositive(t *testing.T) {
+	client := &fakeClient{}
+	d := New(client)
+	for _, page := range []storage.PageRequest{{LookAhead: true}, {BatchSize: 5}} {
+		if _, err := d.ListPlayersByGoalsThreshold(context.Background(), "USA", "WNT", 100, page); err == nil {
+			t.Errorf("%+v: expected an error", page)
+		}
+	}
+	if len(client.queries) != 0 {
+		t.Errorf("sent %d queries", len(client.queries))
+	}
+}
+
+func TestListLimitedPlayersResumesFromToken(t *testing.T) {
+	lastEvaluatedKey := map[string]types.AttributeValue{
+		pk: &types.AttributeValueMemberS{Value: "USA"},
+		sk: &types.AttributeValueMemberS{Value: "WNT#Alex#Morgan"},
+	}
+	d := New(&fakeClient{}, WithSigningKeys(SigningKey{ID: "k1", Secret: []byte("secret")}))
+	item, err := attributevalue.MarshalMap(d.newStatsItem(&storage.StatsRecord{Country: "USA", NationalTeam: "WNT", FirstName: "Alex", LastName: "Morgan"}))
+	if err != nil {
+		t.Fatalf("marshalling item: %v", err)
+	}
+	client := &fakeClient{queryOutputs: []*dynamodb.QueryOutput{
+		{Items: []map[string]types.AttributeValue{item}, Count: 1, LastEvaluatedKey: lastEvaluatedKey},
+		{},
+	}}
+	d.dbClient = client
+
+	page := storage.PageRequest{PageLimit: 1, ScanIndexForward: true}
+	first, err := d.ListLimitedPlayers(context.Background(), "USA", "WNT", page)
+	if err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	if first.NextPageToken == "" {
+		t.Fatalf("expected a next page token")
+	}
+	page.PageToken = first.NextPageToken
+	second, err := d.ListLimitedPlayers(context.Background(), "USA", "WNT", page)
+	if err != nil {
+		t.Fatalf("second page: %v", err)
+	}
+	if first.PrevPageToken != "" {
+		t.Errorf("expected no previous page token on the first page, got %q", first.PrevPageToken)
+	}
+	if second.NextPageToken != "" {
+		t.Errorf("expected no next page token, got %q", second.NextPageToken)
+	}
+	if !reflect.DeepEqual(client.queries[1].ExclusiveStartKey, lastEvaluatedKey) {
+		t.Errorf("ExclusiveStartKey = %v, want %v", client.queries[1].ExclusiveStartKey, lastEvaluatedKey)
+	}
+}
+
+func TestPrevPageTokenQueriesBackward(t *testing.T) {
+	d := New(&fakeClient{})
+	var items []map[string]types.AttributeValue
+	for i, firstName := range []string{"Abby", "Carli", "Kristine", "Mia"} {
+		item, err := attributevalue.MarshalMap(d.newStatsItem(&storage.StatsRecord{Country: "USA", NationalTeam: "WNT", FirstName: firstName, LastName: "Player", Goals: 100 + i}))
+		if err != nil {
+			t.Fatalf("marshalling item: %v", err)
+		}
+		items = append(items, d.toStored(item))
+	}
+	tests := []struct {
+		name     string
+		list     func(ctx context.Context, page storage.PageRequest) (*storage.Page, error)
+		forward  bool
+		startKey func(map[string]types.AttributeValue) map[string]types.AttributeValue
+	}{
+		{"table", func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+			return d.ListPlayersByGoalsThreshold(ctx, "USA", "WNT", 100, page)
+		}, true, d.buildExclusiveStartKey},
+		{"goals index", func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+			return d.ListPlayersByGoalsThresholdSorted(ctx, "USA", "WNT", 100, page)
+		}, false, d.buildExclusiveStartKeyForGSI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{queryOutputs: []*dynamodb.QueryOutput{
+				{Items: items[:2], Count: 2, LastEvaluatedKey: tt.startKey(items[1])},
+				{Items: items[2:], Count: 2, LastEvaluatedKey: tt.startKey(items[3])},
+				// DynamoDB returns the preceding items nearest first
+				{Items: []map[string]types.AttributeValue{items[1], items[0]}, Count: 2, LastEvaluatedKey: tt.startKey(items[0])},
+			}}
+			d.dbClient = client
+
+			page := storage.PageRequest{PageLimit: 2, ScanIndexForward: tt.forward}
+			first, err := tt.list(context.Background(), page)
+			if err != nil {
+				t.Fatalf("first page: %v", err)
+			}
+			page.PageToken = first.NextPageToken
+			second, err := tt.list(context.Background(), page)
+			if err != nil {
+				t.Fatalf("second page: %v", err)
+			}
+			if second.PrevPageToken == "" {
+				t.Fatalf("expected a previous page token on the second page")
+			}
+			page.PageToken = second.PrevPageToken
+			back, err := tt.list(context.Background(), page)
+			if err != nil {
+				t.Fatalf("previous page: %v", err)
+			}
+
+			query := client.queries[2]
+			if aws.ToBool(query.ScanIndexForward) != !tt.forward {
+				t.Errorf("ScanIndexForward = %t, want %t", aws.ToBool(query.ScanIndexForward), !tt.forward)
+			}
+			if want := tt.startKey(items[2]); !reflect.DeepEqual(query.ExclusiveStartKey, want) {
+				t.Errorf("ExclusiveStartKey = %v, want the first item of the second page %v", query.ExclusiveStartKey, want)
+			}
+			if _, ok := query.ExclusiveStartKey[goals]; ok != (query.IndexName != nil) {
+				t.Errorf("ExclusiveStartKey %v carries goals: %t, on index %q", query.ExclusiveStartKey, ok, aws.ToString(query.IndexName))
+			}
+			if len(back.Records) != 2 || back.Records[0].FirstName != "Abby" || back.Records[1].FirstName != "Carli" {
+				t.Errorf("previous page holds %v, want the first page in page order", back.Records)
+			}
+		})
+	}
+}
+
+func TestExactlyFilledLastPageHasNoNextToken(t *testing.T) {
+	d := New(&fakeClient{})
+	var items []map[string]types.AttributeValue
+	for _, firstName := range []string{"Abby", "Carli"} {
+		item, err := attributevalue.MarshalMap(d.newStatsItem(&storage.StatsRecord{Country: "USA", NationalTeam: "WNT", FirstName: firstName, LastName: "Player", Goals: 120}))
+		if err != nil {
+			t.Fatalf("marshalling item: %v", err)
+		}
+		items = append(items, d.toStored(item))
+	}
+	client := &fakeClient{
+		queryOutputs: []*dynamodb.QueryOutput{{Items: items, Count: 2, ScannedCount: 2}},
+		scanOutputs:  []*dynamodb.ScanOutput{{Items: items, Count: 2, ScannedCount: 2}},
+	}
+	d.dbClient = client
+
+	page := storage.PageRequest{PageLimit: 2, BatchSize: 10, ScanIndexForward: true}
+	listed, err := d.ListPlayersByGoalsThreshold(context.Background(), "USA", "WNT", 100, page)
+	if err != nil {
+		t.Fatalf("ListPlayersByGoalsThreshold: %v", err)
+	}
+	scanned, err := d.ScanStatsTable(context.Background(), storage.ScanFilter{}, page)
+	if err != nil {
+		t.Fatalf("ScanStatsTable: %v", err)
+	}
+	for name, resp := range map[string]*storage.Page{"ListPlayersByGoalsThreshold": listed, "ScanStatsTable": scanned} {
+		if len(resp.Records) != 2 {
+			t.Errorf("%s: got %d records, want 2", name, len(resp.Records))
+		}
+		if resp.HasMore || resp.NextPageToken != "" {
+			t.Errorf("%s: HasMore %t with next page token %q after the results ended", name, resp.HasMore, resp.NextPageToken)
+		}
+	}
+}
+
+func TestFilteredQueryGrowsBatchSize(t *testing.T) {
+	d := New(&fakeClient{})
+	item, err := attributevalue.MarshalMap(d.newStatsItem(&storage.StatsRecord{Country: "USA", NationalTeam: "WNT", FirstName: "Alex", LastName: "Morgan", Goals: 119}))
+	if err != nil {
+		t.Fatalf("marshalling item: %v", err)
+	}
+	lastEvaluatedKey := d.buildExclusiveStartKey(d.toStored(item))
+	client := &fakeClient{queryOutputs: []*dynamodb.QueryOutput{
+		{ScannedCount: 2, LastEvaluatedKey: lastEvaluatedKey},
+		{Items: []map[string]types.AttributeValue{item}, Count: 1, ScannedCount: 4, LastEvaluatedKey: lastEvaluatedKey},
+		{ScannedCount: 12, LastEvaluatedKey: lastEvaluatedKey},
+		{},
+	}}
+	d.dbClient = client
+
+	page := storage.PageRequest{PageLimit: 3, BatchSize: 2, MaxBatchSize: 12}
+	if _, err := d.ListPlayersByGoalsThreshold(context.Background(), "USA", "WNT", 100, page); err != nil {
+		t.Fatalf("ListPlayersByGoalsThreshold: %v", err)
+	}
+	// nothing matched: double; one in four matched with two pending: eight;
+	// nothing matched again: double, capped at MaxBatchSize
+	want := []int32{2, 4, 8, 12}
+	if len(client.queries) != len(want) {
+		t.Fatalf("got %d queries, want %d", len(client.queries), len(want))
+	}
+	for i, query := range client.queries {
+		if *query.Limit != want[i] {
+			t.Errorf("query %d: Limit = %d, want %d", i+1, *query.Limit, want[i])
+		}
+	}
+}
+
+func TestReadCapacityBudget(t *testing.T) {
+	lastEvaluatedKey := map[string]types.AttributeValue{
+		pk: &types.AttributeValueMemberS{Value: "USA"},
+		sk: &types.AttributeValueMemberS{Value: "WNT#Tobin#Heath"},
+	}
+	halfUnit := &types.ConsumedCapacity{CapacityUnits: aws.Float64(0.5)}
+	client := &fakeClient{queryOutputs: []*dynamodb.QueryOutput{
+		{ScannedCount: 5, ConsumedCapacity: halfUnit, LastEvaluatedKey: lastEvaluatedKey},
+		{ScannedCount: 5, ConsumedCapacity: halfUnit, LastEvaluatedKey: lastEvaluatedKey},
+		{},
+	}}
+	d := New(client)
+
+	page := storage.PageRequest{PageLimit: 5, MaxReadCapacity: 1}
+	resp, err := d.ListPlayersByGoalsThreshold(context.Background(), "USA", "WNT", 100, page)
+	if err != nil {
+		t.Fatalf("ListPlayersByGoalsThreshold: %v", err)
+	}
+	if len(client.queries) != 2 {
+		t.Fatalf("got %d queries, want 2", len(client.queries))
+	}
+	if resp.ConsumedCapacity == nil || resp.ConsumedCapacity.CapacityUnits != 1 {
+		t.Errorf("ConsumedCapacity = %+v, want 1 capacity unit", resp.ConsumedCapacity)
+	}
+	if resp.ScannedCount != 10 {
+		t.Errorf("ScannedCount = %d, want 10", resp.ScannedCount)
+	}
+	if !resp.BudgetExhausted || resp.NextPageToken == "" {
+		t.Fatalf("got BudgetExhausted %t and next page token %q, want a resumable exhausted page", resp.BudgetExhausted, resp.NextPageToken)
+	}
+
+	page.PageToken = resp.NextPageToken
+	if _, err := d.ListPlayersByGoalsThreshold(context.Background(), "USA", "WNT", 100, page); err != nil {
+		t.Fatalf("resuming: %v", err)
+	}
+	if !reflect.DeepEqual(client.queries[2].ExclusiveStartKey, lastEvaluatedKey) {
+		t.Errorf("ExclusiveStartKey = %v, want %v", client.queries[2].ExclusiveStartKey, lastEvaluatedKey)
+	}
+}
+
+func TestCountFollowsPages(t *testing.T) {
+	lastEvaluatedKey := map[string]types.AttributeValue{
+		pk: &types.AttributeValueMemberS{Value: "USA"},
+		sk: &types.AttributeValueMemberS{Value: "WNT#Mia#Hamm"},
+	}
+	client := &fakeClient{queryOutputs: []*dynamodb.QueryOutput{
+		{Count: 40, ScannedCount: 100, LastEvaluatedKey: lastEvaluatedKey},
+		{Count: 17, ScannedCount: 30},
+	}}
+	got, err := New(client).CountPlayers(context.Background(), "USA", "WNT", storage.CountOptions{})
+	if err != nil {
+		t.Fatalf("CountPlayers: %v", err)
+	}
+	if got.Count != 57 || got.ScannedCount != 130 || !got.Exact {
+		t.Errorf("CountPlayers = %+v, want exactly 57 of 130 scanned", got)
+	}
+	for i, query := range client.queries {
+		if query.Select != types.SelectCount {
+			t.Errorf("query %d: Select = %q, want %q", i+1, query.Select, types.SelectCount)
+		}
+	}
+	if !reflect.DeepEqual(client.queries[1].ExclusiveStartKey, lastEvaluatedKey) {
+		t.Errorf("ExclusiveStartKey = %v, want %v", client.queries[1].ExclusiveStartKey, lastEvaluatedKey)
+	}
+}
+
+func TestCountByGoalsThresholdMatchesTeamPrefix(t *testing.T) {
+	client := &fakeClient{}
+	d := New(client)
+	for _, nationalTeam := range []string{"W", ""} {
+		if _, err := d.CountPlayersByGoalsThreshold(context.Background(), "USA", nationalTeam, 100, storage.CountOptions{}); err != nil {
+			t.Fatalf("CountPlayersByGoalsThreshold(%q): %v", nationalTeam, err)
+		}
+	}
+	if filter := aws.ToString(client.queries[0].FilterExpression); !strings.Contains(filter, "begins_with") {
+		t.Errorf("team filter %q is not a prefix match", filter)
+	}
+	if names := client.queries[0].ExpressionAttributeNames; !hasValue(names, sk) || hasValue(names, nationalTeamAttributeName) {
+		t.Errorf("team filter names %v, want the sort key %q", names, sk)
+	}
+	if filter := client.queries[1].FilterExpression; filter != nil {
+		t.Errorf("empty team filtered with %q", aws.ToString(filter))
+	}
+}
+
+func hasValue(names map[string]string, want string) bool {
+	for _, name := range names {
+		if name == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGoalsRangeKeyConditions(t *testing.T) {
+	tests := []struct {
+		goals *storage.Range
+		want  string
+	}{
+		{storage.Between(50, 100), "BETWEEN"},
+		{storage.Exactly(63), "="},
+		{storage.LessThan(100), "<="},
+		{storage.GreaterThan(100), ">="},
+	}
+	for _, tt := range tests {
+		client := &fakeClient{}
+		_, err := New(client).ListPlayersByGoalsRange(context.Background(), "USA", "WNT", tt.goals, storage.PageRequest{PageLimit: 1})
+		if err != nil {
+			t.Fatalf("ListPlayersByGoalsRange(%s): %v", tt.goals, err)
+		}
+		query := client.queries[0]
+		keyCondition := strings.SplitN(*query.KeyConditionExpression, "AND", 2)
+		if len(keyCondition) != 2 || !strings.Contains(keyCondition[1], tt.want) {
+			t.Errorf("%s: key condition %q has no %s on goals", tt.goals, *query.KeyConditionExpression, tt.want)
+		}
+		if *query.IndexName != defaultIndexName {
+			t.Errorf("%s: IndexName = %q, want %q", tt.goals, *query.IndexName, defaultIndexName)
+		}
+	}
+}
+
+func TestProjectionReadsKeyAttributes(t *testing.T) {
+	client := &fakeClient{}
+	d := New(client, WithGoalsAttribute("goals_scored"))
+	page := storage.PageRequest{PageLimit: 1, Fields: storage.Fields{storage.FieldLastName, storage.FieldGoals}}
+	if _, err := d.ListLimitedPlayers(context.Background(), "USA", "WNT", page); err != nil {
+		t.Fatalf("ListLimitedPlayers: %v", err)
+	}
+	query := client.queries[0]
+	projected := map[string]bool{}
+	for _, placeholder := range strings.Split(*query.ProjectionExpression, ", ") {
+		projected[query.ExpressionAttributeNames[placeholder]] = true
+	}
+	for _, name := range []string{pk, sk, "goals_scored", "last_name"} {
+		if !projected[name] {
+			t.Errorf("projection %q does not read %s: %v", *query.ProjectionExpression, name, query.ExpressionAttributeNames)
+		}
+	}
+	if len(projected) != 4 {
+		t.Errorf("projection reads %d attributes, want 4", len(projected))
+	}
+}
+
+func TestConfiguredSchema(t *testing.T) {
+	client := &fakeClient{}
+	d := New(client,
+		WithTablePrefix("dev_"),
+		WithTableName("player_stats_v2"),
+		WithIndexName("GoalsIndex"),
+		WithKeyAttributes("country_key", "player_key"),
+		WithGoalsAttribute("goals_scored"),
+	)
+	record := &storage.StatsRecord{Country: "USA", NationalTeam: "WNT", FirstName: "Alex", LastName: "Morgan", Goals: 119}
+	if err := d.PutPlayerStats(context.Background(), record); err != nil {
+		t.Fatalf("PutPlayerStats: %v", err)
+	}
+	put := client.puts[0]
+	if *put.TableName != "dev_player_stats_v2" {
+		t.Errorf("TableName = %q, want %q", *put.TableName, "dev_player_stats_v2")
+	}
+	for _, name := range []string{"country_key", "player_key", "goals_scored"} {
+		if _, ok := put.Item[name]; !ok {
+			t.Errorf("stored item has no %q attribute: %v", name, put.Item)
+		}
+	}
+	for _, name := range []string{pk, sk, goals} {
+		if _, ok := put.Item[name]; ok {
+			t.Errorf("stored item still has the default %q attribute", name)
+		}
+	}
+
+	client.getItemOutput = &dynamodb.GetItemOutput{Item: put.Item}
+	got, err := d.GetPlayerStats(context.Background(), "USA", "WNT", "Alex", "Morgan", storage.ReadOptions{})
+	if err != nil {
+		t.Fatalf("GetPlayerStats: %v", err)
+	}
+	if !reflect.DeepEqual(got, record) {
+		t.Errorf("GetPlayerStats = %+v, want %+v", got, record)
+	}
+
+	_, err = d.ListPlayersByGoalsThresholdSorted(context.Background(), "USA", "WNT", 50, storage.PageRequest{PageLimit: 1})
+	if err != nil {
+		t.Fatalf("ListPlayersByGoalsThresholdSorted: %v", err)
+	}
+	query := client.queries[0]
+	if *query.IndexName != "GoalsIndex" {
+		t.Errorf("IndexName = %q, want %q", *query.IndexName, "GoalsIndex")
+	}
+	names := map[string]bool{}
+	for _, name := range query.ExpressionAttributeNames {
+		names[name] = true
+	}
+	if !names["country_key"] || !names["goals_scored"] {
+		t.Errorf("key condition does not use the configured names: %v", query.ExpressionAttributeNames)
+	}
+}
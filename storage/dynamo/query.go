@@ -0,0 +1,102 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"pagination/storage"
+)
+
+// QueryPlayers pages through the players matching query. The query's
+// direction replaces page.ScanIndexForward.
+func (p *playerStats) QueryPlayers(ctx context.Context, query storage.PlayerQuery, page storage.PageRequest) (*storage.Page, error) {
+	page.ScanIndexForward = !query.Descending
+	compiled, err := p.compilePlayerQuery(query, storage.ReadOptions{ConsistentRead: page.ConsistentRead})
+	if err != nil {
+		return nil, err
+	}
+	binding := queryBinding("QueryPlayers", p.table(), query.String(), compiled.indexName)
+	read, err := p.readForPage(page, compiled.indexName, binding)
+	if err != nil {
+		return nil, err
+	}
+	return p.queryPage(ctx, compiled.input, read, compiled.startKey, binding)
+}
+
+// PlanPlayerQuery reports the access path QueryPlayers takes for query with a
+// page request of the same consistency as opts.
+func (p *playerStats) PlanPlayerQuery(query storage.PlayerQuery, opts storage.ReadOptions) (*storage.Plan, error) {
+	return storage.PlanQuery(query, p.indexes(), opts)
+}
+
+// indexes describes the table and its goals index to the planner.
+func (p *playerStats) indexes() []storage.IndexDescriptor {
+	return []storage.IndexDescriptor{
+		{SortBy: storage.SortByPlayer, SortKey: p.keys.sortKey, Consistent: true},
+		{Name: p.indexName, SortBy: storage.SortByGoals, SortKey: p.keys.goals},
+	}
+}
+
+// compiledQuery is a PlayerQuery translated into a query on one index.
+type compiledQuery struct {
+	input     *dynamodb.QueryInput
+	indexName string // empty for the base table
+	startKey  func(map[string]types.AttributeValue) map[string]types.AttributeValue
+}
+
+// compilePlayerQuery turns the conditions the planned index can serve into
+// key conditions and everything else into filters. The table serves the team
+// prefix through begins_with on the sort key, the goals index the goals range.
+func (p *playerStats) compilePlayerQuery(query storage.PlayerQuery, opts storage.ReadOptions) (*compiledQuery, error) {
+	if strings.Contains(query.TeamPrefix, identifierSeparator) {
+		return nil, fmt.Errorf("team prefix %q must not contain %q", query.TeamPrefix, identifierSeparator)
+	}
+	plan, err := p.PlanPlayerQuery(query, opts)
+	if err != nil {
+		return nil, err
+	}
+	compiled := &compiledQuery{input: &dynamodb.QueryInput{TableName: aws.String(p.table())}}
+	var keyCond expression.KeyConditionBuilder
+	var filters []expression.ConditionBuilder
+	if plan.Index.SortBy == storage.SortByGoals {
+		compiled.indexName = p.indexName
+		compiled.input.IndexName = aws.String(p.indexName)
+		compiled.startKey = p.buildExclusiveStartKeyForGSI
+		keyCond = p.goalsKeyCondition(query.Country, query.Goals)
+		if query.TeamPrefix != "" {
+			filters = append(filters, expression.Name(nationalTeamAttributeName).BeginsWith(query.TeamPrefix))
+		}
+	} else {
+		compiled.startKey = p.buildExclusiveStartKey
+		keyCond = expression.Key(p.keys.partitionKey).Equal(expression.Value(query.Country))
+		if query.TeamPrefix != "" {
+			keyCond = keyCond.And(expression.Key(p.keys.sortKey).BeginsWith(query.TeamPrefix))
+		}
+		filters = append(filters, rangeConditions(p.keys.goals, query.Goals)...)
+	}
+	if query.FirstNamePrefix != "" {
+		filters = append(filters, expression.Name(firstNameAttributeName).BeginsWith(query.FirstNamePrefix))
+	}
+	filters = append(filters, rangeConditions(assistsAttributeName, query.Assists)...)
+	filters = append(filters, rangeConditions(appearancesAttributeName, query.Appearances)...)
+
+	builder := expression.NewBuilder().WithKeyCondition(keyCond)
+	if len(filters) > 0 {
+		builder = builder.WithFilter(allOf(filters))
+	}
+	expr, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	compiled.input.ExpressionAttributeNames = expr.Names()
+	compiled.input.ExpressionAttributeValues = expr.Values()
+	compiled.input.KeyConditionExpression = expr.KeyCondition()
+	compiled.input.FilterExpression = expr.Filter()
+	return compiled, nil
+}
@@ -0,0 +1,52 @@
+package dynamo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"pagination/storage"
+)
+
+func TestCompilePlayerQueryChoosesIndex(t *testing.T) {
+	d := New(&fakeClient{})
+	tests := []struct {
+		query        storage.PlayerQuery
+		indexName    string
+		keyCondition string
+		filter       string
+	}{
+		{
+			query:        storage.PlayerQuery{Country: "USA", TeamPrefix: "WNT", Goals: storage.Between(100, 150)},
+			indexName:    defaultIndexName,
+			keyCondition: "BETWEEN",
+			filter:       "begins_with",
+		},
+		{
+			query:        storage.PlayerQuery{Country: "USA", TeamPrefix: "WNT", Goals: storage.AtLeast(100)},
+			keyCondition: "begins_with",
+			filter:       ">=",
+		},
+		{
+			query:        storage.PlayerQuery{Country: "USA", FirstNamePrefix: "Al"},
+			keyCondition: "=",
+			filter:       "begins_with",
+		},
+	}
+	for _, tt := range tests {
+		compiled, err := d.compilePlayerQuery(tt.query, storage.ReadOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if got := aws.ToString(compiled.input.IndexName); got != tt.indexName || compiled.indexName != tt.indexName {
+			t.Errorf("%s: IndexName = %q, want %q", tt.query, got, tt.indexName)
+		}
+		if got := aws.ToString(compiled.input.KeyConditionExpression); !strings.Contains(got, tt.keyCondition) {
+			t.Errorf("%s: key condition %q has no %s", tt.query, got, tt.keyCondition)
+		}
+		if got := aws.ToString(compiled.input.FilterExpression); !strings.Contains(got, tt.filter) {
+			t.Errorf("%s: filter %q has no %s", tt.query, got, tt.filter)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const tableStatusPollInterval = 2 * time.Second
+
+// SchemaError lists every way the live table differs from the schema the query
+// builders expect.
+type SchemaError struct {
+	TableName   string
+	Differences []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("table %s does not match the expected schema:\n  %s", e.TableName, strings.Join(e.Differences, "\n  "))
+}
+
+func (p *playerStats) attributeDefinitions() []types.AttributeDefinition {
+	return []types.AttributeDefinition{
+		{AttributeName: aws.String(p.keys.partitionKey), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String(p.keys.sortKey), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String(p.keys.goals), AttributeType: types.ScalarAttributeTypeN},
+	}
+}
+
+func (p *playerStats) tableKeySchema() []types.KeySchemaElement {
+	return []types.KeySchemaElement{
+		{AttributeName: aws.String(p.keys.partitionKey), KeyType: types.KeyTypeHash},
+		{AttributeName: aws.String(p.keys.sortKey), KeyType: types.KeyTypeRange},
+	}
+}
+
+func (p *playerStats) indexKeySchema() []types.KeySchemaElement {
+	return []types.KeySchemaElement{
+		{AttributeName: aws.String(p.keys.partitionKey), KeyType: types.KeyTypeHash},
+		{AttributeName: aws.String(p.keys.goals), KeyType: types.KeyTypeRange},
+	}
+}
+
+// indexProjection is ALL because goals queries filter on national_team and
+// return complete records.
+func (p *playerStats) indexProjection() *types.Projection {
+	return &types.Projection{ProjectionType: types.ProjectionTypeAll}
+}
+
+// CreateTable creates the stats table and its goals index unless they already
+// exist, then waits until both are ACTIVE. It is safe to call on every start.
+func (d *Dynamo) CreateTable(ctx context.Context) error {
+	_, err := d.client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:            aws.String(d.table()),
+		AttributeDefinitions: d.attributeDefinitions(),
+		KeySchema:            d.tableKeySchema(),
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{
+				IndexName:  aws.String(d.indexName),
+				KeySchema:  d.indexKeySchema(),
+				Projection: d.indexProjection(),
+			},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	var inUse *types.ResourceInUseException
+	if err != nil && !errors.As(err, &inUse) {
+		return fmt.Errorf("creating table %s: %w", d.table(), err)
+	}
+	table, err := d.waitUntilActive(ctx)
+	if err != nil {
+		return err
+	}
+	if findIndex(table, d.indexName) != nil {
+		return nil
+	}
+	// the table predates the index, as when it was created by the original
+	// shell script before GSI1 was added
+	_, err = d.client.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+		TableName: aws.String(d.table()),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String(d.keys.partitionKey), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String(d.keys.goals), AttributeType: types.ScalarAttributeTypeN},
+		},
+		GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+			{
+				Create: &types.CreateGlobalSecondaryIndexAction{
+					IndexName:             aws.String(d.indexName),
+					KeySchema:             d.indexKeySchema(),
+					Projection:            d.indexProjection(),
+					ProvisionedThroughput: provisionedThroughputFor(table),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("adding index %s to table %s: %w", d.indexName, d.table(), err)
+	}
+	_, err = d.waitUntilActive(ctx)
+	return err
+}
+
+// provisionedThroughputFor returns the throughput a new index needs on the
+// given table: none on on-demand tables, the table's own otherwise.
+func provisionedThroughputFor(table *types.TableDescription) *types.ProvisionedThroughput {
+	if table.BillingModeSummary != nil && table.BillingModeSummary.BillingMode == types.BillingModePayPerRequest {
+		return nil
+	}
+	if table.ProvisionedThroughput == nil || aws.ToInt64(table.ProvisionedThroughput.ReadCapacityUnits) == 0 {
+		return nil
+	}
+	return &types.ProvisionedThroughput{
+		ReadCapacityUnits:  table.ProvisionedThroughput.ReadCapacityUnits,
+		WriteCapacityUnits: table.ProvisionedThroughput.WriteCapacityUnits,
+	}
+}
+
+// waitUntilActive polls the table until it and all of its indexes are ACTIVE
+// or ctx is done.
+func (d *Dynamo) waitUntilActive(ctx context.Context) (*types.TableDescription, error) {
+	for {
+		resp, err := d.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(d.table())})
+		if err != nil {
+			return nil, fmt.Errorf("describing table %s: %w", d.table(), err)
+		}
+		if isActive(resp.Table) {
+			return resp.Table, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for table %s to become active: %w", d.table(), ctx.Err())
+		case <-time.After(tableStatusPollInterval):
+		}
+	}
+}
+
+func isActive(table *types.TableDescription) bool {
+	if table.TableStatus != types.TableStatusActive {
+		return false
+	}
+	for _, index := range table.GlobalSecondaryIndexes {
+		if index.IndexStatus != types.IndexStatusActive {
+			return false
+		}
+	}
+	return true
+}
+
+func findIndex(table *types.TableDescription, indexName string) *types.GlobalSecondaryIndexDescription {
+	for i := range table.GlobalSecondaryIndexes {
+		if aws.ToString(table.GlobalSecondaryIndexes[i].IndexName) == indexName {
+			return &table.GlobalSecondaryIndexes[i]
+		}
+	}
+	return nil
+}
+
+// VerifySchema checks that the live table's key schema, key attribute types
+// and goals index match what the query builders expect, returning a
+// *SchemaError describing every difference otherwise.
+func (d *Dynamo) VerifySchema(ctx context.Context) error {
+	resp, err := d.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(d.table())})
+	if err != nil {
+		return fmt.Errorf("describing table %s: %w", d.table(), err)
+	}
+	table := resp.Table
+	var differences []string
+
+	differences = append(differences, diffKeySchema("table", table.KeySchema, d.tableKeySchema())...)
+
+	attributeTypes := make(map[string]types.ScalarAttributeType, len(table.AttributeDefinitions))
+	for _, definition := range table.AttributeDefinitions {
+		attributeTypes[aws.ToString(definition.AttributeName)] = definition.AttributeType
+	}
+	for _, want := range d.attributeDefinitions() {
+		name := aws.ToString(want.AttributeName)
+		got, ok := attributeTypes[name]
+		if !ok {
+			differences = append(differences, fmt.Sprintf("attribute %s: not defined, want type %s", name, want.AttributeType))
+		} else if got != want.AttributeType {
+			differences = append(differences, fmt.Sprintf("attribute %s: type %s, want %s", name, got, want.AttributeType))
+		}
+	}
+
+	index := findIndex(table, d.indexName)
+	if index == nil {
+		differences = append(differences, fmt.Sprintf("index %s: missing", d.indexName))
+	} else {
+		differences = append(differences, diffKeySchema("index "+d.indexName, index.KeySchema, d.indexKeySchema())...)
+		want := d.indexProjection().ProjectionType
+		if index.Projection == nil || index.Projection.ProjectionType != want {
+			var got types.ProjectionType
+			if index.Projection != nil {
+				got = index.Projection.ProjectionType
+			}
+			differences = append(differences, fmt.Sprintf("index %s: projection %s, want %s", d.indexName, got, want))
+		}
+	}
+
+	if len(differences) > 0 {
+		return &SchemaError{TableName: d.table(), Differences: differences}
+	}
+	return nil
+}
+
+func diffKeySchema(subject string, got []types.KeySchemaElement, want []types.KeySchemaElement) []string {
+	gotByType := make(map[types.KeyType]string, len(got))
+	for _, element := range got {
+		gotByType[element.KeyType] = aws.ToString(element.AttributeName)
+	}
+	var differences []string
+	for _, element := range want {
+		name := aws.ToString(element.AttributeName)
+		actual, ok := gotByType[element.KeyType]
+		if !ok {
+			differences = append(differences, fmt.Sprintf("%s: no %s key, want %s", subject, element.KeyType, name))
+		} else if actual != name {
+			differences = append(differences, fmt.Sprintf("%s: %s key is %s, want %s", subject, element.KeyType, actual, name))
+		}
+	}
+	return differences
+}
@@ -0,0 +1,138 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type schemaClient struct {
+	fakeClient
+	table   *types.TableDescription
+	created bool
+	updates []*dynamodb.UpdateTableInput
+}
+
+func (c *schemaClient) CreateTable(ctx context.Context, params *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	if c.table != nil {
+		return nil, &types.ResourceInUseException{Message: aws.String("table exists")}
+	}
+	c.created = true
+	c.table = &types.TableDescription{
+		TableName:            params.TableName,
+		TableStatus:          types.TableStatusActive,
+		KeySchema:            params.KeySchema,
+		AttributeDefinitions: params.AttributeDefinitions,
+	}
+	for _, index := range params.GlobalSecondaryIndexes {
+		c.table.GlobalSecondaryIndexes = append(c.table.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+			IndexName:   index.IndexName,
+			IndexStatus: types.IndexStatusActive,
+			KeySchema:   index.KeySchema,
+			Projection:  index.Projection,
+		})
+	}
+	return &dynamodb.CreateTableOutput{TableDescription: c.table}, nil
+}
+
+func (c *schemaClient) UpdateTable(ctx context.Context, params *dynamodb.UpdateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+	c.updates = append(c.updates, params)
+	c.table.AttributeDefinitions = append(c.table.AttributeDefinitions, params.AttributeDefinitions[1])
+	for _, update := range params.GlobalSecondaryIndexUpdates {
+		c.table.GlobalSecondaryIndexes = append(c.table.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+			IndexName:   update.Create.IndexName,
+			IndexStatus: types.IndexStatusActive,
+			KeySchema:   update.Create.KeySchema,
+			Projection:  update.Create.Projection,
+		})
+	}
+	return &dynamodb.UpdateTableOutput{}, nil
+}
+
+func (c *schemaClient) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	if c.table == nil {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("no table")}
+	}
+	return &dynamodb.DescribeTableOutput{Table: c.table}, nil
+}
+
+func TestCreateTableIsIdempotent(t *testing.T) {
+	client := &schemaClient{}
+	d := New(client)
+	for i := 0; i < 2; i++ {
+		if err := d.CreateTable(context.Background()); err != nil {
+			t.Fatalf("CreateTable call %d: %v", i+1, err)
+		}
+	}
+	if !client.created {
+		t.Fatalf("table was not created")
+	}
+	if err := d.VerifySchema(context.Background()); err != nil {
+		t.Fatalf("VerifySchema after CreateTable: %v", err)
+	}
+}
+
+func TestCreateTableAddsMissingIndex(t *testing.T) {
+	d := New(&fakeClient{})
+	client := &schemaClient{table: &types.TableDescription{
+		TableName:            aws.String(d.table()),
+		TableStatus:          types.TableStatusActive,
+		KeySchema:            d.tableKeySchema(),
+		AttributeDefinitions: d.attributeDefinitions()[:2],
+	}}
+	d = New(client)
+	if err := d.CreateTable(context.Background()); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	if len(client.updates) != 1 {
+		t.Fatalf("got %d UpdateTable calls, want 1", len(client.updates))
+	}
+	if err := d.VerifySchema(context.Background()); err != nil {
+		t.Fatalf("VerifySchema: %v", err)
+	}
+}
+
+func TestVerifySchemaReportsDifferences(t *testing.T) {
+	client := &schemaClient{table: &types.TableDescription{
+		TableStatus: types.TableStatusActive,
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("player"), KeyType: types.KeyTypeRange},
+		},
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("player"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("goals"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndexDescription{
+			{
+				IndexName:   aws.String("GSI1"),
+				IndexStatus: types.IndexStatusActive,
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+					{AttributeName: aws.String("goals"), KeyType: types.KeyTypeRange},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly},
+			},
+		},
+	}}
+	err := New(client).VerifySchema(context.Background())
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("got error %v, want a *SchemaError", err)
+	}
+	want := []string{
+		"table: RANGE key is player, want sk",
+		"attribute sk: not defined, want type S",
+		"attribute goals: type S, want N",
+		"index GSI1: projection KEYS_ONLY, want ALL",
+	}
+	if got := strings.Join(schemaErr.Differences, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("differences:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
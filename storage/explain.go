@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrExplainUnsupported is returned when a read asks for an explanation its
+// backend cannot record.
+var ErrExplainUnsupported = errors.New("explain is not supported by this backend")
+
+// Explainer records the requests a read sends to its backend and reports them
+// through String. It is set as the Explain option of a read; each backend only
+// accepts its own implementation and fails reads that carry another with
+// ErrExplainUnsupported.
+type Explainer interface {
+	fmt.Stringer
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Field names a StatsRecord field for projections.
+type Field string
+
+const (
+	FieldCountry      Field = "country"
+	FieldNationalTeam Field = "national_team"
+	FieldFirstName    Field = "first_name"
+	FieldLastName     Field = "last_name"
+	FieldGoals        Field = "goals"
+	FieldAssists      Field = "assists"
+	FieldAppearances  Field = "appearances"
+)
+
+// AllFields lists every StatsRecord field.
+var AllFields = Fields{FieldCountry, FieldNationalTeam, FieldFirstName, FieldLastName, FieldGoals, FieldAssists, FieldAppearances}
+
+// Fields selects the StatsRecord fields a read returns. Nil selects every
+// field; fields left out are zero in the returned records.
+type Fields []Field
+
+func (f Fields) Validate() error {
+	if f != nil && len(f) == 0 {
+		return fmt.Errorf("a projection needs at least one field")
+	}
+	for _, field := range f {
+		if !AllFields.Has(field) {
+			return fmt.Errorf("unknown field %q", field)
+		}
+	}
+	return nil
+}
+
+// Has reports whether field is selected.
+func (f Fields) Has(field Field) bool {
+	if f == nil {
+		return true
+	}
+	for _, selected := range f {
+		if selected == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Project zeroes the fields of record that are not selected.
+func (f Fields) Project(record *StatsRecord) {
+	if f == nil {
+		return
+	}
+	projected := StatsRecord{}
+	if f.Has(FieldCountry) {
+		projected.Country = record.Country
+	}
+	if f.Has(FieldNationalTeam) {
+		projected.NationalTeam = record.NationalTeam
+	}
+	if f.Has(FieldFirstName) {
+		projected.FirstName = record.FirstName
+	}
+	if f.Has(FieldLastName) {
+		projected.LastName = record.LastName
+	}
+	if f.Has(FieldGoals) {
+		projected.Goals = record.Goals
+	}
+	if f.Has(FieldAssists) {
+		projected.Assists = record.Assists
+	}
+	if f.Has(FieldAppearances) {
+		projected.Appearances = record.Appearances
+	}
+	*record = projected
+}
+
+func (f Fields) String() string {
+	if f == nil {
+		return "all"
+	}
+	names := make([]string, len(f))
+	for i, field := range f {
+		names[i] = string(field)
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"strconv"
+
+	"pagination/storage"
+)
+
+// ForgePageToken builds a ListPlayersByGoalsThreshold token the way any client
+// can, as in-memory tokens are not signed.
+func ForgePageToken(s storage.Storage, country string, nationalTeam string, goalThreshold int, pageLimit int32) (string, error) {
+	c := &cursor{PageLimit: pageLimit, Binding: queryBinding("ListPlayersByGoalsThreshold", country, nationalTeam, strconv.Itoa(goalThreshold))}
+	return c.encode()
+}
@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"pagination/storage"
+)
+
+const (
+	identifierSeparator = "#"
+	gsi                 = "GSI1"
+)
+
+var _ storage.Storage = (*Memory)(nil)
+
+// Memory is a storage.Storage kept in process memory. It mirrors the key layout
+// and pagination behaviour of the DynamoDB backend: records are partitioned by
+// country and ordered by <NationalTeam>#<FirstName>#<LastName>, with GSI1
+// ordering a partition by goals.
+type Memory struct {
+	mu         sync.RWMutex
+	partitions map[string]map[string]storage.StatsRecord
+}
+
+func New() *Memory {
+	return &Memory{
+		partitions: make(map[string]map[string]storage.StatsRecord),
+	}
+}
+
+// consistentRead mirrors the DynamoDB backend, whose goals index cannot serve
+// strongly consistent reads. Reads here are always consistent.
+func consistentRead(consistent bool, indexName string) error {
+	if consistent && indexName != "" {
+		return fmt.Errorf("%w: %s", storage.ErrConsistentReadOnIndex, indexName)
+	}
+	return nil
+}
+
+// explainUnsupported fails reads that ask for an explanation: there are no
+// backend requests here to record.
+func explainUnsupported(explain storage.Explainer) error {
+	if explain != nil {
+		return fmt.Errorf("%w: the memory backend sends no requests", storage.ErrExplainUnsupported)
+	}
+	return nil
+}
+
+// item is a stored record together with its sort key.
+type item struct {
+	sortKey string
+	record  storage.StatsRecord
+}
+
+func buildSortKey(nationalTeam string, firstName string, lastName string) string {
+	return fmt.Sprintf("%s%s%s%s%s", nationalTeam, identifierSeparator, firstName, identifierSeparator, lastName)
+}
+
+func (m *Memory) GetPlayerStats(ctx context.Context, country string, nationalTeam string, firstName string, lastName string, opts storage.ReadOptions) (*storage.StatsRecord, error) {
+	if err := explainUnsupported(opts.Explain); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	record, ok := m.partitions[country][buildSortKey(nationalTeam, firstName, lastName)]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &record, nil
+}
+
+func (m *Memory) PutPlayerStats(ctx context.Context, record *storage.StatsRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	partition, ok := m.partitions[record.Country]
+	if !ok {
+		partition = make(map[string]storage.StatsRecord)
+		m.partitions[record.Country] = partition
+	}
+	partition[buildSortKey(record.NationalTeam, record.FirstName, record.LastName)] = *record
+	return nil
+}
+
+// partition returns the items of a country ordered by sort key.
+func (m *Memory) partition(country string) []*item {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	items := make([]*item, 0, len(m.partitions[country]))
+	for sortKey, record := range m.partitions[country] {
+		items = append(items, &item{sortKey: sortKey, record: record})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].sortKey < items[j].sortKey
+	})
+	return items
+}
+
+// table returns every item ordered by country and sort key.
+func (m *Memory) table() []*item {
+	m.mu.RLock()
+	countries := make([]string, 0, len(m.partitions))
+	for country := range m.partitions {
+		countries = append(countries, country)
+	}
+	m.mu.RUnlock()
+	sort.Strings(countries)
+	var items []*item
+	for _, country := range countries {
+		items = append(items, m.partition(country)...)
+	}
+	return items
+}
+
+// byGoals reorders items the way GSI1 does, by goals with the sort key breaking
+// ties.
+func byGoals(items []*item) []*item {
+	sort.SliceStable(items, func(i, j int) bool {
+		return goalsLess(items[i], items[j])
+	})
+	return items
+}
+
+func goalsLess(a *item, b *item) bool {
+	if a.record.Goals != b.record.Goals {
+		return a.record.Goals < b.record.Goals
+	}
+	return a.sortKey < b.sortKey
+}
+
+func tableLess(a *item, b *item) bool {
+	if a.record.Country != b.record.Country {
+		return a.record.Country < b.record.Country
+	}
+	return a.sortKey < b.sortKey
+}
+
+func sortKeyLess(a *item, b *item) bool {
+	return a.sortKey < b.sortKey
+}
+
+func reversed(items []*item) []*item {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+	return items
+}
+
+func records(items []*item) []*storage.StatsRecord {
+	records := make([]*storage.StatsRecord, 0, len(items))
+	for _, it := range items {
+		record := it.record
+		records = append(records, &record)
+	}
+	return records
+}
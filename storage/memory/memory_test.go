@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"pagination/storage"
+	"pagination/storage/memory"
+	"pagination/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return memory.New()
+	}, storagetest.WithTokenForger(memory.ForgePageToken))
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"pagination/storage"
+)
+
+// cursor is the decoded form of a page token. Last identifies the final item
+// of the previous page and is nil for the first page; for a Backward cursor it
+// is the first item of the following page.
+type cursor struct {
+	PageLimit        int32    `json:"l"`
+	ScanIndexForward bool     `json:"f,omitempty"`
+	IndexName        string   `json:"i,omitempty"`
+	Binding          string   `json:"b"`
+	Last             *lastKey `json:"k,omitempty"`
+	Backward         bool     `json:"r,omitempty"`
+}
+
+// pageRead is a paginated read: the cursor it resumes from and the options of
+// the request, which are not carried in page tokens.
+type pageRead struct {
+	*cursor
+	maxScanned int32
+	lookAhead  bool
+	fields     storage.Fields
+}
+
+type lastKey struct {
+	Country string `json:"c"`
+	SortKey string `json:"s"`
+	Goals   int    `json:"g,omitempty"`
+}
+
+func (k *lastKey) item() *item {
+	return &item{sortKey: k.SortKey, record: storage.StatsRecord{Country: k.Country, Goals: k.Goals}}
+}
+
+func queryBinding(operation string, params ...string) string {
+	return strings.Join(append([]string{operation}, params...), "\x00")
+}
+
+func readForPage(page storage.PageRequest, indexName string, binding string) (*pageRead, error) {
+	if page.BatchSize < 0 || page.MaxBatchSize < 0 {
+		return nil, fmt.Errorf("batch sizes must not be negative, got %d and %d", page.BatchSize, page.MaxBatchSize)
+	}
+	if err := page.Fields.Validate(); err != nil {
+		return nil, err
+	}
+	if err := explainUnsupported(page.Explain); err != nil {
+		return nil, err
+	}
+	if page.MaxScanned < 0 || page.MaxReadCapacity < 0 {
+		return nil, fmt.Errorf("read budgets must not be negative, got %d items and %g capacity units", page.MaxScanned, page.MaxReadCapacity)
+	}
+	if page.PageToken == "" {
+		if page.PageLimit <= 0 {
+			return nil, fmt.Errorf("page limit must be positive, got %d", page.PageLimit)
+		}
+		c := &cursor{PageLimit: page.PageLimit, ScanIndexForward: page.ScanIndexForward, IndexName: indexName, Binding: binding}
+		return newPageRead(c, page), nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(page.PageToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", storage.ErrInvalidPageToken, err)
+	}
+	var c cursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, fmt.Errorf("%w: %v", storage.ErrInvalidPageToken, err)
+	}
+	if c.Binding != binding || c.IndexName != indexName {
+		return nil, fmt.Errorf("%w: token was issued for a different query", storage.ErrInvalidPageToken)
+	}
+	if c.PageLimit <= 0 {
+		return nil, fmt.Errorf("%w: page limit must be positive, got %d", storage.ErrInvalidPageToken, c.PageLimit)
+	}
+	return newPageRead(&c, page), nil
+}
+
+func newPageRead(c *cursor, page storage.PageRequest) *pageRead {
+	return &pageRead{cursor: c, maxScanned: page.MaxScanned, lookAhead: page.LookAhead, fields: page.Fields}
+}
+
+// at returns a copy of the cursor positioned at it.
+func (c *cursor) at(it *item, backward bool) *cursor {
+	next := *c
+	next.Last = &lastKey{Country: it.record.Country, SortKey: it.sortKey, Goals: it.record.Goals}
+	next.Backward = backward
+	return &next
+}
+
+func (c *cursor) encode() (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload), nil
+}
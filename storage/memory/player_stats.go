@@ -0,0 +1,363 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"pagination/storage"
+)
+
+func (m *Memory) ScanStatsTable(ctx context.Context, filter storage.ScanFilter, page storage.PageRequest) (*storage.Page, error) {
+	// scans always walk the table forwards
+	page.ScanIndexForward = true
+	binding := queryBinding("ScanStatsTable", filter.String())
+	read, err := readForPage(page, "", binding)
+	if err != nil {
+		return nil, err
+	}
+	// read budgets and look-ahead only apply to the List* queries, as in the
+	// DynamoDB backend
+	read.maxScanned, read.lookAhead = 0, false
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	result, err := paginate(m.table(), read, tableLess, func(it *item) bool {
+		return inSegment(it, filter) && filter.Matches(&it.record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// like DynamoDB scans, there is no way back
+	result.PrevPageToken = ""
+	return result, nil
+}
+
+func (m *Memory) ScanAllStats(ctx context.Context, filter storage.ScanFilter, opts storage.ReadOptions, fn func(page *storage.Page) error) error {
+	if err := explainUnsupported(opts.Explain); err != nil {
+		return err
+	}
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+	var matches []*item
+	var scanned int32
+	for _, it := range m.table() {
+		if !inSegment(it, filter) {
+			continue
+		}
+		scanned++
+		if filter.Matches(&it.record) {
+			matches = append(matches, it)
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	return fn(&storage.Page{Records: records(matches), Count: int32(len(matches)), ScannedCount: scanned})
+}
+
+func (m *Memory) ListPlayers(ctx context.Context, country string, nationalTeam string, opts storage.ReadOptions) ([]*storage.StatsRecord, error) {
+	if err := explainUnsupported(opts.Explain); err != nil {
+		return nil, err
+	}
+	return records(m.teamItems(country, nationalTeam)), nil
+}
+
+func (m *Memory) ListAllPlayers(ctx context.Context, country string, nationalTeam string, opts storage.ReadOptions) ([]*storage.StatsRecord, error) {
+	if err := explainUnsupported(opts.Explain); err != nil {
+		return nil, err
+	}
+	return records(m.teamItems(country, nationalTeam)), nil
+}
+
+func (m *Memory) StreamAllPlayers(ctx context.Context, country string, nationalTeam string, opts storage.ReadOptions, fn func(record *storage.StatsRecord) error) error {
+	if err := explainUnsupported(opts.Explain); err != nil {
+		return err
+	}
+	for _, record := range records(m.teamItems(country, nationalTeam)) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ListLimitedPlayers(ctx context.Context, country string, nationalTeam string, page storage.PageRequest) (*storage.Page, error) {
+	// the DynamoDB backend leaves ScanIndexForward unset for this query, so
+	// results are always in ascending sort key order
+	page.ScanIndexForward = true
+	binding := queryBinding("ListLimitedPlayers", country, nationalTeam)
+	read, err := readForPage(page, "", binding)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(m.teamItems(country, nationalTeam), read, sortKeyLess, nil)
+}
+
+func (m *Memory) ListPlayersByGoalsThreshold(ctx context.Context, country string, nationalTeam string, goalThreshold int, page storage.PageRequest) (*storage.Page, error) {
+	binding := queryBinding("ListPlayersByGoalsThreshold", country, nationalTeam, strconv.Itoa(goalThreshold))
+	read, err := readForPage(page, "", binding)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(m.teamItems(country, nationalTeam), read, sortKeyLess, func(it *item) bool {
+		return it.record.Goals >= goalThreshold
+	})
+}
+
+func (m *Memory) ListPlayersByGoalsThresholdSorted(ctx context.Context, country string, nationalTeam string, goalThreshold int, page storage.PageRequest) (*storage.Page, error) {
+	if err := consistentRead(page.ConsistentRead, gsi); err != nil {
+		return nil, err
+	}
+	binding := queryBinding("ListPlayersByGoalsThresholdSorted", country, nationalTeam, strconv.Itoa(goalThreshold), gsi)
+	read, err := readForPage(page, gsi, binding)
+	if err != nil {
+		return nil, err
+	}
+	return m.paginateGoals(country, nationalTeam, storage.AtLeast(goalThreshold), read)
+}
+
+func (m *Memory) ListPlayersByGoalsRange(ctx context.Context, country string, nationalTeam string, goalsRange *storage.Range, page storage.PageRequest) (*storage.Page, error) {
+	if err := goalsRange.Validate(); err != nil {
+		return nil, err
+	}
+	if err := consistentRead(page.ConsistentRead, gsi); err != nil {
+		return nil, err
+	}
+	binding := queryBinding("ListPlayersByGoalsRange", country, nationalTeam, goalsRange.String(), gsi)
+	read, err := readForPage(page, gsi, binding)
+	if err != nil {
+		return nil, err
+	}
+	return m.paginateGoals(country, nationalTeam, goalsRange, read)
+}
+
+// QueryPlayers reads the planned index, applying the conditions that index
+// serves as key conditions and the rest as filters.
+func (m *Memory) QueryPlayers(ctx context.Context, query storage.PlayerQuery, page storage.PageRequest) (*storage.Page, error) {
+	if strings.Contains(query.TeamPrefix, identifierSeparator) {
+		return nil, fmt.Errorf("team prefix %q must not contain %q", query.TeamPrefix, identifierSeparator)
+	}
+	plan, err := m.PlanPlayerQuery(query, storage.ReadOptions{ConsistentRead: page.ConsistentRead})
+	if err != nil {
+		return nil, err
+	}
+	page.ScanIndexForward = !query.Descending
+	var items []*item
+	indexName, less := "", sortKeyLess
+	if plan.Index.SortBy == storage.SortByGoals {
+		indexName, less = gsi, goalsLess
+		for _, it := range m.partition(query.Country) {
+			if query.Goals.Contains(it.record.Goals) {
+				items = append(items, it)
+			}
+		}
+		items = byGoals(items)
+	} else {
+		for _, it := range m.partition(query.Country) {
+			if strings.HasPrefix(it.sortKey, query.TeamPrefix) {
+				items = append(items, it)
+			}
+		}
+	}
+	binding := queryBinding("QueryPlayers", query.String(), indexName)
+	read, err := readForPage(page, indexName, binding)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(items, read, less, func(it *item) bool {
+		return query.Matches(&it.record)
+	})
+}
+
+// PlanPlayerQuery plans query against the same indexes as the DynamoDB
+// backend, named after its default key attributes.
+func (m *Memory) PlanPlayerQuery(query storage.PlayerQuery, opts storage.ReadOptions) (*storage.Plan, error) {
+	return storage.PlanQuery(query, []storage.IndexDescriptor{
+		{SortBy: storage.SortByPlayer, SortKey: "sk", Consistent: true},
+		{Name: gsi, SortBy: storage.SortByGoals, SortKey: "goals"},
+	}, opts)
+}
+
+// paginateGoals mirrors a query on the goals index: the range is the key
+// condition and the national team a filter.
+func (m *Memory) paginateGoals(country string, nationalTeam string, goalsRange *storage.Range, read *pageRead) (*storage.Page, error) {
+	var items []*item
+	for _, it := range m.partition(country) {
+		if goalsRange.Contains(it.record.Goals) {
+			items = append(items, it)
+		}
+	}
+	return paginate(byGoals(items), read, goalsLess, func(it *item) bool {
+		return it.record.NationalTeam == nationalTeam
+	})
+}
+
+func (m *Memory) CountPlayers(ctx context.Context, country string, nationalTeam string, opts storage.CountOptions) (*storage.Count, error) {
+	return count(m.teamItems(country, nationalTeam), nil, opts)
+}
+
+// CountPlayersByGoalsThreshold mirrors the DynamoDB backend, which counts on the
+// goals index: the items above the threshold are read and filtered by the
+// team prefix of their sort key. Strongly consistent counts read the team's
+// items and filter by goals, as the table does.
+func (m *Memory) CountPlayersByGoalsThreshold(ctx context.Context, country string, nationalTeam string, goalThreshold int, opts storage.CountOptions) (*storage.Count, error) {
+	if opts.ConsistentRead {
+		return count(m.teamItems(country, nationalTeam), func(it *item) bool {
+			return it.record.Goals >= goalThreshold
+		}, opts)
+	}
+	var items []*item
+	for _, it := range byGoals(m.partition(country)) {
+		if it.record.Goals >= goalThreshold {
+			items = append(items, it)
+		}
+	}
+	return count(items, func(it *item) bool {
+		return strings.HasPrefix(it.sortKey, nationalTeam)
+	}, opts)
+}
+
+// count counts the items that pass match, stopping after opts.MaxScanned
+// items. Reads are not metered, so opts.MaxReadCapacity is ignored.
+func count(items []*item, match func(*item) bool, opts storage.CountOptions) (*storage.Count, error) {
+	if opts.MaxScanned < 0 || opts.MaxReadCapacity < 0 {
+		return nil, fmt.Errorf("read budgets must not be negative, got %d items and %g capacity units", opts.MaxScanned, opts.MaxReadCapacity)
+	}
+	if err := explainUnsupported(opts.Explain); err != nil {
+		return nil, err
+	}
+	result := &storage.Count{Exact: true}
+	for _, it := range items {
+		if opts.MaxScanned > 0 && result.ScannedCount == int64(opts.MaxScanned) {
+			result.Exact = false
+			break
+		}
+		result.ScannedCount++
+		if match == nil || match(it) {
+			result.Count++
+		}
+	}
+	return result, nil
+}
+
+// inSegment assigns items to scan segments by a hash of their primary key.
+func inSegment(it *item, filter storage.ScanFilter) bool {
+	if filter.TotalSegments == 0 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(it.record.Country))
+	h.Write([]byte(identifierSeparator))
+	h.Write([]byte(it.sortKey))
+	return int32(h.Sum32()%uint32(filter.TotalSegments)) == filter.Segment
+}
+
+// teamItems mirrors the key condition pk = country AND begins_with(sk, nationalTeam).
+func (m *Memory) teamItems(country string, nationalTeam string) []*item {
+	var items []*item
+	for _, it := range m.partition(country) {
+		if strings.HasPrefix(it.sortKey, nationalTeam) {
+			items = append(items, it)
+		}
+	}
+	return items
+}
+
+// paginate returns the page of items that follow the cursor position and pass
+// match, or with a backward cursor the page that precedes it. items must be in
+// ascending order according to less. As with the DynamoDB backend, a page
+// that fills up carries a token towards the items after it even if no further
+// matching items remain unless read.lookAhead is set, and read.maxScanned
+// bounds the items evaluated.
+func paginate(items []*item, read *pageRead, less func(a *item, b *item) bool, match func(*item) bool) (*storage.Page, error) {
+	forward := read.ScanIndexForward != read.Backward
+	if !forward {
+		items = reversed(items)
+	}
+	var selected []*item
+	var last *item
+	if read.Last != nil {
+		last = read.Last.item()
+	}
+	target := int(read.PageLimit)
+	if read.lookAhead {
+		target++
+	}
+	// resume is where the walk stopped, nil if it ran out of items
+	var resume *item
+	budgetExhausted := false
+	var scanned int32
+	for i, it := range items {
+		if last != nil {
+			if forward && !less(last, it) || !forward && !less(it, last) {
+				continue
+			}
+		}
+		if read.maxScanned > 0 && scanned == read.maxScanned {
+			resume, budgetExhausted = items[i-1], true
+			break
+		}
+		scanned++
+		if match != nil && !match(it) {
+			continue
+		}
+		selected = append(selected, it)
+		if len(selected) == target {
+			// the look-ahead item only proves that more follow
+			selected = selected[:read.PageLimit]
+			resume = selected[len(selected)-1]
+			break
+		}
+	}
+	if read.Backward {
+		selected = reversed(selected)
+	}
+	page := &storage.Page{
+		Records:         records(selected),
+		Fields:          read.fields,
+		BudgetExhausted: budgetExhausted,
+		Count:           int32(len(selected)),
+		ScannedCount:    scanned,
+	}
+	for _, record := range page.Records {
+		read.fields.Project(record)
+	}
+	if len(selected) == 0 && !budgetExhausted {
+		return page, nil
+	}
+	ahead, behind := resume, last
+	if len(selected) > 0 {
+		behind = selected[0]
+		if read.Backward {
+			behind = selected[len(selected)-1]
+		}
+	}
+	nextAt, prevAt := ahead, behind
+	if read.Backward {
+		nextAt, prevAt = behind, ahead
+	}
+	// the first page has nothing before it
+	if !read.Backward && read.Last == nil {
+		prevAt = nil
+	}
+	var err error
+	if nextAt != nil {
+		if page.NextPageToken, err = read.at(nextAt, false).encode(); err != nil {
+			return nil, err
+		}
+		page.HasMore = true
+	}
+	if prevAt != nil {
+		if page.PrevPageToken, err = read.at(prevAt, true).encode(); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrPageOutOfRange is returned by PageNavigator.Page for page numbers past the
+// last page.
+var ErrPageOutOfRange = errors.New("page out of range")
+
+// PageNavigator serves pages by number ("jump to page 7") on top of the keyset
+// page tokens of a paginated call. It remembers the token that starts every
+// page boundary it has crossed, so revisiting a page costs a single fetch.
+//
+// Reaching a page beyond the furthest one seen has to walk every page in
+// between: jumping from page 1 straight to page n of a fresh navigator costs n
+// fetches, and each of them reads up to PageLimit matching items plus whatever
+// the backend filtered out on the way. Cached checkpoints are page tokens and
+// expire with them; on expiry create a new navigator.
+//
+// Page numbers are only stable when every page is full, so the first request
+// must not set a read budget: a page cut short by MaxScanned or
+// MaxReadCapacity would be numbered like any other, and the numbering would
+// shift with the items the backend filtered out.
+//
+// A PageNavigator is safe for concurrent use.
+type PageNavigator struct {
+	fetch PageFunc
+	first PageRequest
+
+	mu sync.Mutex
+	// tokens[i] is the page token that fetches page i+1.
+	tokens []string
+	// last is the number of the final page once it has been seen, else zero.
+	last int
+}
+
+func NewPageNavigator(fetch PageFunc, first PageRequest) (*PageNavigator, error) {
+	if first.MaxScanned != 0 || first.MaxReadCapacity != 0 {
+		return nil, fmt.Errorf("page navigation needs full pages, got read budgets of %d items and %g capacity units", first.MaxScanned, first.MaxReadCapacity)
+	}
+	return &PageNavigator{fetch: fetch, first: first, tokens: []string{first.PageToken}}, nil
+}
+
+// Page returns page number n, counting from 1.
+func (n *PageNavigator) Page(ctx context.Context, number int) (*Page, error) {
+	if number < 1 {
+		return nil, fmt.Errorf("%w: page %d", ErrPageOutOfRange, number)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.last > 0 && number > n.last {
+		return nil, fmt.Errorf("%w: page %d of %d", ErrPageOutOfRange, number, n.last)
+	}
+	// start from the closest checkpoint at or before the requested page
+	current := len(n.tokens)
+	if number < current {
+		current = number
+	}
+	for {
+		request := n.first
+		request.PageToken = n.tokens[current-1]
+		page, err := n.fetch(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		if len(page.Records) == 0 && current > 1 {
+			// the empty page that follows a last page which filled up exactly
+			n.last = current - 1
+			n.tokens = n.tokens[:current-1]
+			return nil, fmt.Errorf("%w: page %d of %d", ErrPageOutOfRange, number, n.last)
+		}
+		if page.NextPageToken == "" {
+			n.last = current
+		} else if current == len(n.tokens) {
+			n.tokens = append(n.tokens, page.NextPageToken)
+		}
+		if current == number {
+			return page, nil
+		}
+		if page.NextPageToken == "" {
+			return nil, fmt.Errorf("%w: page %d of %d", ErrPageOutOfRange, number, n.last)
+		}
+		current++
+	}
+}
+
+// KnownPages returns how many pages can be reached with a single fetch and
+// whether that is all of them.
+func (n *PageNavigator) KnownPages() (pages int, complete bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.last > 0 {
+		return n.last, true
+	}
+	return len(n.tokens), false
+}
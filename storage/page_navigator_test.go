@@ -0,0 +1,79 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"pagination/storage"
+	"pagination/storage/memory"
+)
+
+func TestPageNavigatorCachesCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	s := memory.New()
+	seedPlayers(t, s, 20)
+	fetches := 0
+	navigator, err := storage.NewPageNavigator(func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+		fetches++
+		return s.ListLimitedPlayers(ctx, "Country0", "WNT", page)
+	}, storage.PageRequest{PageLimit: 1, ScanIndexForward: true})
+	if err != nil {
+		t.Fatalf("NewPageNavigator: %v", err)
+	}
+
+	// sort keys order the last names 0, 10, 15, 5
+	steps := []struct {
+		page     int
+		lastName string
+		fetches  int
+	}{
+		{3, "15", 3},
+		{2, "10", 1},
+		{3, "15", 1},
+		{4, "5", 1},
+		{1, "0", 1},
+	}
+	for _, step := range steps {
+		fetches = 0
+		page, err := navigator.Page(ctx, step.page)
+		if err != nil {
+			t.Fatalf("page %d: %v", step.page, err)
+		}
+		if len(page.Records) != 1 || page.Records[0].LastName != step.lastName {
+			t.Errorf("page %d: got %v, want last name %s", step.page, page.Records, step.lastName)
+		}
+		if fetches != step.fetches {
+			t.Errorf("page %d: took %d fetches, want %d", step.page, fetches, step.fetches)
+		}
+	}
+
+	if _, err := navigator.Page(ctx, 5); !errors.Is(err, storage.ErrPageOutOfRange) {
+		t.Fatalf("page 5: got error %v, want %v", err, storage.ErrPageOutOfRange)
+	}
+	if pages, complete := navigator.KnownPages(); pages != 4 || !complete {
+		t.Errorf("KnownPages = %d, %t, want 4, true", pages, complete)
+	}
+	fetches = 0
+	if _, err := navigator.Page(ctx, 9); !errors.Is(err, storage.ErrPageOutOfRange) {
+		t.Fatalf("page 9: got error %v, want %v", err, storage.ErrPageOutOfRange)
+	}
+	if fetches != 0 {
+		t.Errorf("page past the known last page took %d fetches", fetches)
+	}
+}
+
+func TestPageNavigatorRejectsReadBudgets(t *testing.T) {
+	fetch := func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+		t.Fatal("fetched a page")
+		return nil, nil
+	}
+	for _, first := range []storage.PageRequest{
+		{PageLimit: 2, MaxScanned: 5},
+		{PageLimit: 2, MaxReadCapacity: 1},
+	} {
+		if _, err := storage.NewPageNavigator(fetch, first); err == nil {
+			t.Errorf("NewPageNavigator(%+v): expected an error", first)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"iter"
+)
+
+// PageFunc fetches a single page, usually by calling one of the paginated
+// StatsReader methods with fixed query arguments.
+type PageFunc func(ctx context.Context, page PageRequest) (*Page, error)
+
+// Pager walks the pages of a paginated call, following page tokens so callers
+// do not have to:
+//
+//	pager := storage.NewPager(func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+//		return reader.ListLimitedPlayers(ctx, country, nationalTeam, page)
+//	}, storage.PageRequest{PageLimit: 10, ScanIndexForward: true})
+//	for pager.Next(ctx) {
+//		use(pager.Page().Records)
+//	}
+//	if err := pager.Err(); err != nil {
+//		...
+//	}
+//
+// Pages and Records offer the same walk as range loops.
+//
+// Pages that come back empty, such as the trailing page DynamoDB reports when
+// the last page filled up exactly, are skipped.
+type Pager struct {
+	fetch PageFunc
+	next  PageRequest
+	page  *Page
+	err   error
+	done  bool
+}
+
+func NewPager(fetch PageFunc, first PageRequest) *Pager {
+	return &Pager{fetch: fetch, next: first}
+}
+
+// Next fetches the next non-empty page. It returns false once the pages are
+// exhausted or a fetch failed, after which Err reports the failure.
+func (p *Pager) Next(ctx context.Context) bool {
+	for !p.done && p.err == nil {
+		page, err := p.fetch(ctx, p.next)
+		if err != nil {
+			p.err = err
+			return false
+		}
+		if page.NextPageToken == "" {
+			p.done = true
+		} else {
+			p.next.PageToken = page.NextPageToken
+		}
+		if len(page.Records) > 0 {
+			p.page = page
+			return true
+		}
+	}
+	p.page = nil
+	return false
+}
+
+// Page returns the page fetched by the last successful call to Next.
+func (p *Pager) Page() *Page {
+	return p.page
+}
+
+func (p *Pager) Err() error {
+	return p.err
+}
+
+// Pages returns an iterator over the remaining pages for use with range. Check
+// Err once the loop ends.
+func (p *Pager) Pages(ctx context.Context) iter.Seq[*Page] {
+	return func(yield func(page *Page) bool) {
+		for p.Next(ctx) {
+			if !yield(p.page) {
+				return
+			}
+		}
+	}
+}
+
+// Records returns an iterator over the records of the remaining pages for use
+// with range. Check Err once the loop ends.
+func (p *Pager) Records(ctx context.Context) iter.Seq[*StatsRecord] {
+	return func(yield func(record *StatsRecord) bool) {
+		for p.Next(ctx) {
+			for _, record := range p.page.Records {
+				if !yield(record) {
+					return
+				}
+			}
+		}
+	}
+}
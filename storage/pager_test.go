@@ -0,0 +1,97 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"pagination/storage"
+	"pagination/storage/memory"
+)
+
+func TestPagerWalksAllPages(t *testing.T) {
+	s := memory.New()
+	seedPlayers(t, s, 10)
+	pager := storage.NewPager(func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+		return s.ListLimitedPlayers(ctx, "Country0", "WNT", page)
+	}, storage.PageRequest{PageLimit: 1, ScanIndexForward: true})
+
+	pages := 0
+	for pager.Next(context.Background()) {
+		pages++
+		if len(pager.Page().Records) != 1 {
+			t.Errorf("page %d has %d records, want 1", pages, len(pager.Page().Records))
+		}
+	}
+	if err := pager.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	// the memory backend, like DynamoDB, reports one more page after the last
+	// full one; the pager must not surface it
+	if pages != 2 {
+		t.Fatalf("got %d pages, want 2", pages)
+	}
+	if pager.Next(context.Background()) {
+		t.Errorf("Next after exhaustion returned true")
+	}
+}
+
+func TestPagerRecords(t *testing.T) {
+	s := memory.New()
+	seedPlayers(t, s, 20)
+	pager := storage.NewPager(func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+		return s.ListPlayersByGoalsThresholdSorted(ctx, "Country1", "WNT", 5, page)
+	}, storage.PageRequest{PageLimit: 2})
+
+	var goals []int
+	for record := range pager.Records(context.Background()) {
+		goals = append(goals, record.Goals)
+	}
+	if err := pager.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	want := []int{16, 11, 6}
+	if len(goals) != len(want) {
+		t.Fatalf("got goals %v, want %v", goals, want)
+	}
+	for i := range want {
+		if goals[i] != want[i] {
+			t.Fatalf("got goals %v, want %v", goals, want)
+		}
+	}
+}
+
+func TestPagerRecordsStopsEarly(t *testing.T) {
+	s := memory.New()
+	seedPlayers(t, s, 20)
+	pager := storage.NewPager(func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+		return s.ListPlayersByGoalsThresholdSorted(ctx, "Country1", "WNT", 5, page)
+	}, storage.PageRequest{PageLimit: 2})
+
+	var goals []int
+	for record := range pager.Records(context.Background()) {
+		goals = append(goals, record.Goals)
+		if len(goals) == 2 {
+			break
+		}
+	}
+	if err := pager.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if len(goals) != 2 || goals[0] != 16 || goals[1] != 11 {
+		t.Fatalf("got goals %v, want [16 11]", goals)
+	}
+}
+
+func TestPagerStopsOnError(t *testing.T) {
+	failure := errors.New("boom")
+	pager := storage.NewPager(func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+		return nil, failure
+	}, storage.PageRequest{PageLimit: 1})
+	if pager.Next(context.Background()) {
+		t.Fatalf("Next returned true on a failing fetch")
+	}
+	if !errors.Is(pager.Err(), failure) {
+		t.Fatalf("Err = %v, want %v", pager.Err(), failure)
+	}
+}
@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const defaultParallelScanPageLimit = 100
+
+// ParallelScanOptions configures ParallelScan. Filter.Segment and
+// Filter.TotalSegments are set per worker and ignored here.
+type ParallelScanOptions struct {
+	Filter        ScanFilter
+	TotalSegments int32
+	Workers       int   // defaults to TotalSegments
+	PageLimit     int32 // defaults to 100
+
+	// CheckpointFile, when set, records the progress of every segment after
+	// each page. A scan started with an existing checkpoint skips completed
+	// segments and resumes the others where they stopped. The file is kept
+	// once the scan finishes; delete it to start over.
+	CheckpointFile string
+}
+
+// scanCheckpoint is the on-disk form of a parallel scan's progress.
+type scanCheckpoint struct {
+	Filter        string              `json:"filter"`
+	TotalSegments int32               `json:"total_segments"`
+	Segments      []segmentCheckpoint `json:"segments"`
+}
+
+type segmentCheckpoint struct {
+	PageToken string `json:"page_token,omitempty"`
+	Done      bool   `json:"done,omitempty"`
+}
+
+// ParallelScan reads the whole table through ScanStatsTable, splitting it into
+// TotalSegments segments that are scanned by Workers goroutines. fn is called
+// with every page and must be safe for concurrent use; the first error it or
+// the backend returns cancels the scan.
+//
+// With a checkpoint file, a page is recorded as processed only after fn
+// returns, so after a crash the page being processed at the time is delivered
+// again. The checkpoint holds backend page tokens, which a resumed scan can only
+// use while they are valid: the DynamoDB backend needs the same signing keys
+// and a token TTL longer than the pause. A resumed scan reads the next page of
+// every unfinished segment before delivering any, so a stale checkpoint fails
+// up front.
+func ParallelScan(ctx context.Context, reader StatsReader, opts ParallelScanOptions, fn func(segment int32, page *Page) error) error {
+	if opts.TotalSegments <= 0 {
+		return fmt.Errorf("parallel scan needs at least one segment, got %d", opts.TotalSegments)
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = int(opts.TotalSegments)
+	}
+	if opts.PageLimit <= 0 {
+		opts.PageLimit = defaultParallelScanPageLimit
+	}
+	opts.Filter.Segment, opts.Filter.TotalSegments = 0, 0
+
+	checkpoint, err := loadScanCheckpoint(opts)
+	if err != nil {
+		return err
+	}
+	resumed, err := resumeSegments(ctx, reader, opts, checkpoint)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firstErr error
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	// record stores a segment's progress and persists the checkpoint.
+	record := func(segment int32, progress segmentCheckpoint) error {
+		mu.Lock()
+		defer mu.Unlock()
+		checkpoint.Segments[segment] = progress
+		if opts.CheckpointFile == "" {
+			return nil
+		}
+		return checkpoint.save(opts.CheckpointFile)
+	}
+
+	segments := make(chan int32)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for segment := range segments {
+				mu.Lock()
+				progress := checkpoint.Segments[segment]
+				mu.Unlock()
+				if err := scanSegment(ctx, reader, opts, segment, progress.PageToken, resumed[segment], fn, record); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+	for segment := int32(0); segment < opts.TotalSegments; segment++ {
+		if checkpoint.Segments[segment].Done {
+			continue
+		}
+		select {
+		case segments <- segment:
+		case <-ctx.Done():
+		}
+	}
+	close(segments)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// resumeSegments reads the page each unfinished segment of checkpoint resumes
+// from, failing if the backend no longer accepts a recorded page token.
+func resumeSegments(ctx context.Context, reader StatsReader, opts ParallelScanOptions, checkpoint *scanCheckpoint) (map[int32]*Page, error) {
+	resumed := make(map[int32]*Page)
+	for segment, progress := range checkpoint.Segments {
+		if progress.Done || progress.PageToken == "" {
+			continue
+		}
+		page := PageRequest{PageLimit: opts.PageLimit, ScanIndexForward: true, PageToken: progress.PageToken}
+		resp, err := reader.ScanStatsTable(ctx, segmentFilter(opts, int32(segment)), page)
+		if errors.Is(err, ErrInvalidPageToken) {
+			return nil, fmt.Errorf("checkpoint %s cannot be resumed, delete it to start over: segment %d: %w", opts.CheckpointFile, segment, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("scanning segment %d: %w", segment, err)
+		}
+		resumed[int32(segment)] = resp
+	}
+	return resumed, nil
+}
+
+func segmentFilter(opts ParallelScanOptions, segment int32) ScanFilter {
+	filter := opts.Filter
+	filter.Segment = segment
+	filter.TotalSegments = opts.TotalSegments
+	return filter
+}
+
+// scanSegment scans a segment from pageToken, starting with the already read
+// page resp if it is not nil.
+func scanSegment(ctx context.Context, reader StatsReader, opts ParallelScanOptions, segment int32, pageToken string, resp *Page,
+	fn func(segment int32, page *Page) error, record func(segment int32, progress segmentCheckpoint) error) error {
+	filter := segmentFilter(opts, segment)
+	page := PageRequest{PageLimit: opts.PageLimit, ScanIndexForward: true, PageToken: pageToken}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if resp == nil {
+			var err error
+			if resp, err = reader.ScanStatsTable(ctx, filter, page); err != nil {
+				return fmt.Errorf("scanning segment %d: %w", segment, err)
+			}
+		}
+		if len(resp.Records) > 0 {
+			if err := fn(segment, resp); err != nil {
+				return err
+			}
+		}
+		progress := segmentCheckpoint{PageToken: resp.NextPageToken, Done: resp.NextPageToken == ""}
+		if err := record(segment, progress); err != nil {
+			return fmt.Errorf("saving checkpoint: %w", err)
+		}
+		if progress.Done {
+			return nil
+		}
+		page.PageToken = resp.NextPageToken
+		resp = nil
+	}
+}
+
+func loadScanCheckpoint(opts ParallelScanOptions) (*scanCheckpoint, error) {
+	checkpoint := &scanCheckpoint{
+		Filter:        opts.Filter.String(),
+		TotalSegments: opts.TotalSegments,
+		Segments:      make([]segmentCheckpoint, opts.TotalSegments),
+	}
+	if opts.CheckpointFile == "" {
+		return checkpoint, nil
+	}
+	data, err := os.ReadFile(opts.CheckpointFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading checkpoint: %w", err)
+	}
+	var saved scanCheckpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("reading checkpoint %s: %w", opts.CheckpointFile, err)
+	}
+	if saved.Filter != checkpoint.Filter || saved.TotalSegments != checkpoint.TotalSegments || len(saved.Segments) != int(saved.TotalSegments) {
+		return nil, fmt.Errorf("checkpoint %s belongs to a different scan (%s, %d segments)", opts.CheckpointFile, saved.Filter, saved.TotalSegments)
+	}
+	return &saved, nil
+}
+
+// save writes the checkpoint through a temporary file so that a crash never
+// leaves a partially written checkpoint behind.
+func (c *scanCheckpoint) save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,162 @@
+package storage_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"pagination/storage"
+	"pagination/storage/memory"
+)
+
+func seedPlayers(t *testing.T, s storage.Storage, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		err := s.PutPlayerStats(context.Background(), &storage.StatsRecord{
+			Country:      fmt.Sprintf("Country%d", i%5),
+			NationalTeam: "WNT",
+			FirstName:    "Player",
+			LastName:     fmt.Sprint(i),
+			Goals:        i,
+		})
+		if err != nil {
+			t.Fatalf("PutPlayerStats: %v", err)
+		}
+	}
+}
+
+type scanCollector struct {
+	mu      sync.Mutex
+	seen    map[string]int
+	perSeg  map[int32]int
+	calls   int
+	failAt  int
+	failErr error
+}
+
+func (c *scanCollector) collect(segment int32, page *storage.Page) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if c.failAt > 0 && c.calls == c.failAt {
+		return c.failErr
+	}
+	for _, record := range page.Records {
+		c.seen[record.Country+"/"+record.LastName]++
+	}
+	c.perSeg[segment]++
+	return nil
+}
+
+func newScanCollector() *scanCollector {
+	return &scanCollector{seen: map[string]int{}, perSeg: map[int32]int{}}
+}
+
+func TestParallelScanReadsEveryRecordOnce(t *testing.T) {
+	s := memory.New()
+	seedPlayers(t, s, 40)
+	collector := newScanCollector()
+	err := storage.ParallelScan(context.Background(), s, storage.ParallelScanOptions{TotalSegments: 4, Workers: 2, PageLimit: 3}, collector.collect)
+	if err != nil {
+		t.Fatalf("ParallelScan: %v", err)
+	}
+	if len(collector.seen) != 40 {
+		t.Fatalf("saw %d distinct records, want 40", len(collector.seen))
+	}
+	for key, count := range collector.seen {
+		if count != 1 {
+			t.Errorf("record %s delivered %d times", key, count)
+		}
+	}
+}
+
+func TestParallelScanResumesFromCheckpoint(t *testing.T) {
+	s := memory.New()
+	seedPlayers(t, s, 40)
+	checkpointFile := filepath.Join(t.TempDir(), "scan.json")
+	opts := storage.ParallelScanOptions{TotalSegments: 4, Workers: 1, PageLimit: 2, CheckpointFile: checkpointFile}
+
+	crash := errors.New("crash")
+	first := newScanCollector()
+	first.failAt = 8
+	first.failErr = crash
+	if err := storage.ParallelScan(context.Background(), s, opts, first.collect); !errors.Is(err, crash) {
+		t.Fatalf("first run: got error %v, want %v", err, crash)
+	}
+
+	data, err := os.ReadFile(checkpointFile)
+	if err != nil {
+		t.Fatalf("reading checkpoint: %v", err)
+	}
+	var checkpoint struct {
+		Segments []struct {
+			Done bool `json:"done"`
+		} `json:"segments"`
+	}
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		t.Fatalf("decoding checkpoint: %v", err)
+	}
+
+	second := newScanCollector()
+	if err := storage.ParallelScan(context.Background(), s, opts, second.collect); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	completed := 0
+	for segment, progress := range checkpoint.Segments {
+		if progress.Done {
+			completed++
+			if second.perSeg[int32(segment)] > 0 {
+				t.Errorf("completed segment %d was read again", segment)
+			}
+		}
+	}
+	if completed == 0 {
+		t.Errorf("expected the first run to complete at least one segment")
+	}
+	for key := range second.seen {
+		first.seen[key] += second.seen[key]
+	}
+	if len(first.seen) != 40 {
+		t.Fatalf("saw %d distinct records across both runs, want 40", len(first.seen))
+	}
+	for key, count := range first.seen {
+		if count != 1 {
+			t.Errorf("record %s delivered %d times across both runs", key, count)
+		}
+	}
+
+	if err := storage.ParallelScan(context.Background(), s, storage.ParallelScanOptions{TotalSegments: 2, CheckpointFile: checkpointFile}, second.collect); err == nil {
+		t.Errorf("expected an error resuming with a different segment count")
+	}
+}
+
+func TestParallelScanRejectsStaleCheckpoint(t *testing.T) {
+	s := memory.New()
+	seedPlayers(t, s, 10)
+	checkpointFile := filepath.Join(t.TempDir(), "scan.json")
+	stale, err := json.Marshal(map[string]interface{}{
+		"filter":         storage.ScanFilter{}.String(),
+		"total_segments": 2,
+		"segments":       []map[string]interface{}{{"done": true}, {"page_token": "expired"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(checkpointFile, stale, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	collector := newScanCollector()
+	err = storage.ParallelScan(context.Background(), s, storage.ParallelScanOptions{TotalSegments: 2, CheckpointFile: checkpointFile}, collector.collect)
+	if !errors.Is(err, storage.ErrInvalidPageToken) {
+		t.Fatalf("got error %v, want ErrInvalidPageToken", err)
+	}
+	if collector.calls != 0 {
+		t.Errorf("%d pages delivered before the stale checkpoint was detected", collector.calls)
+	}
+}
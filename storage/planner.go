@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IndexDescriptor describes a way of reading a country's players: the table
+// itself or one of its secondary indexes. Name is empty for the table, and
+// SortKey is the attribute the index is sorted on, which plans name in their
+// key conditions. Consistent marks the ones that serve strongly consistent
+// reads.
+type IndexDescriptor struct {
+	Name       string
+	SortBy     SortField
+	SortKey    string
+	Consistent bool
+}
+
+func (d IndexDescriptor) String() string {
+	if d.Name == "" {
+		return "table"
+	}
+	return "index " + d.Name
+}
+
+// Plan is the access path chosen for a PlayerQuery: the index to read, the
+// conditions it serves as key conditions and those left to filters.
+type Plan struct {
+	Index         IndexDescriptor
+	KeyConditions []string
+	Filters       []string
+	Reason        string
+}
+
+func (p *Plan) String() string {
+	return fmt.Sprintf("%s: key conditions [%s], filters [%s] (%s)",
+		p.Index, strings.Join(p.KeyConditions, ", "), strings.Join(p.Filters, ", "), p.Reason)
+}
+
+// PlanQuery picks one of indexes to serve query. An explicit SortBy is served
+// by the first index sorted that way. With SortAny, each index is scored by how
+// selective the predicate on its sort key is likely to be, and the best one
+// wins, the earlier one on ties:
+//
+//	goals exactly n            4
+//	goals between a and b      3
+//	team prefix                2
+//	goals at least or at most  1
+//	nothing on the sort key    0
+//
+// The scores encode rough expectations of the data, such as teams splitting a
+// country in two, rather than statistics.
+//
+// With opts.ConsistentRead only Consistent indexes are considered, and an
+// explicit SortBy only served by other indexes fails with
+// ErrConsistentReadOnIndex.
+func PlanQuery(query PlayerQuery, indexes []IndexDescriptor, opts ReadOptions) (*Plan, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("no indexes to plan %s on", query)
+	}
+	if opts.ConsistentRead {
+		var consistent []IndexDescriptor
+		for _, index := range indexes {
+			if index.Consistent {
+				consistent = append(consistent, index)
+			}
+		}
+		if len(consistent) == 0 {
+			return nil, fmt.Errorf("%w: no index to plan %s on", ErrConsistentReadOnIndex, query)
+		}
+		indexes = consistent
+	}
+	var chosen *IndexDescriptor
+	var reason string
+	if query.SortBy != SortAny {
+		for i := range indexes {
+			if indexes[i].SortBy == query.SortBy {
+				chosen = &indexes[i]
+				break
+			}
+		}
+		if chosen == nil && opts.ConsistentRead {
+			return nil, fmt.Errorf("%w: sorting by %s needs one", ErrConsistentReadOnIndex, query.SortBy)
+		}
+		if chosen == nil {
+			return nil, fmt.Errorf("no index is sorted by %s", query.SortBy)
+		}
+		reason = "sorted by " + query.SortBy.String()
+	} else {
+		best := -1
+		for i := range indexes {
+			if score := keySelectivity(query, indexes[i].SortBy); score > best {
+				best, chosen = score, &indexes[i]
+			}
+		}
+		if best == 0 {
+			reason = "no condition on a sort key"
+		} else {
+			reason = fmt.Sprintf("most selective sort key condition (score %d)", best)
+		}
+	}
+	return planFor(query, *chosen, reason), nil
+}
+
+// keySelectivity scores the predicate query places on the sort key of an index
+// sorted by field; see PlanQuery.
+func keySelectivity(query PlayerQuery, field SortField) int {
+	switch field {
+	case SortByGoals:
+		switch {
+		case unbounded(query.Goals):
+			return 0
+		case query.Goals.Min != nil && query.Goals.Max != nil && *query.Goals.Min == *query.Goals.Max:
+			return 4
+		case query.Goals.Min != nil && query.Goals.Max != nil:
+			return 3
+		}
+		return 1
+	case SortByPlayer:
+		if query.TeamPrefix != "" {
+			return 2
+		}
+	}
+	return 0
+}
+
+func planFor(query PlayerQuery, index IndexDescriptor, reason string) *Plan {
+	plan := &Plan{Index: index, Reason: reason, KeyConditions: []string{fmt.Sprintf("country = %q", query.Country)}}
+	goals := !unbounded(query.Goals)
+	if index.SortBy == SortByGoals {
+		if goals {
+			plan.KeyConditions = append(plan.KeyConditions, index.SortKey+" in "+query.Goals.String())
+		}
+		if query.TeamPrefix != "" {
+			plan.Filters = append(plan.Filters, fmt.Sprintf("national_team begins with %q", query.TeamPrefix))
+		}
+	} else {
+		// the team leads the sort key, so the prefix matches the key itself
+		if query.TeamPrefix != "" {
+			plan.KeyConditions = append(plan.KeyConditions, fmt.Sprintf("%s begins with %q", index.SortKey, query.TeamPrefix))
+		}
+		if goals {
+			plan.Filters = append(plan.Filters, "goals in "+query.Goals.String())
+		}
+	}
+	if query.FirstNamePrefix != "" {
+		plan.Filters = append(plan.Filters, fmt.Sprintf("first_name begins with %q", query.FirstNamePrefix))
+	}
+	if !unbounded(query.Assists) {
+		plan.Filters = append(plan.Filters, "assists in "+query.Assists.String())
+	}
+	if !unbounded(query.Appearances) {
+		plan.Filters = append(plan.Filters, "appearances in "+query.Appearances.String())
+	}
+	return plan
+}
+
+// unbounded reports whether r places no condition on its attribute.
+func unbounded(r *Range) bool {
+	return r == nil || r.Min == nil && r.Max == nil
+}
@@ -0,0 +1,90 @@
+package storage_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"pagination/storage"
+)
+
+func TestPlanQuery(t *testing.T) {
+	indexes := []storage.IndexDescriptor{
+		{SortBy: storage.SortByPlayer, SortKey: "sk", Consistent: true},
+		{Name: "GSI1", SortBy: storage.SortByGoals, SortKey: "goals"},
+	}
+	tests := []struct {
+		name  string
+		query storage.PlayerQuery
+		index string
+	}{
+		{"nothing on a sort key", storage.PlayerQuery{Country: "USA", FirstNamePrefix: "A"}, ""},
+		{"team prefix", storage.PlayerQuery{Country: "USA", TeamPrefix: "WNT"}, ""},
+		{"team prefix beats open goals range", storage.PlayerQuery{Country: "USA", TeamPrefix: "WNT", Goals: storage.AtLeast(100)}, ""},
+		{"goals between beats team prefix", storage.PlayerQuery{Country: "USA", TeamPrefix: "WNT", Goals: storage.Between(100, 120)}, "GSI1"},
+		{"open goals range", storage.PlayerQuery{Country: "USA", Goals: storage.AtMost(10)}, "GSI1"},
+		{"explicit sort", storage.PlayerQuery{Country: "USA", Goals: storage.Exactly(10), SortBy: storage.SortByPlayer}, ""},
+	}
+	for _, tt := range tests {
+		plan, err := storage.PlanQuery(tt.query, indexes, storage.ReadOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if plan.Index.Name != tt.index {
+			t.Errorf("%s: planned %s, want %q", tt.name, plan, tt.index)
+		}
+	}
+
+	_, err := storage.PlanQuery(storage.PlayerQuery{Country: "USA", SortBy: storage.SortByGoals}, indexes[:1], storage.ReadOptions{})
+	if err == nil {
+		t.Errorf("sorting by goals without a goals index: expected an error")
+	}
+}
+
+func TestPlanQueryConditions(t *testing.T) {
+	indexes := []storage.IndexDescriptor{
+		{SortBy: storage.SortByPlayer, SortKey: "sk", Consistent: true},
+		{Name: "GSI1", SortBy: storage.SortByGoals, SortKey: "goals"},
+	}
+	tests := []struct {
+		name          string
+		query         storage.PlayerQuery
+		keyConditions []string
+		filters       []string
+	}{
+		{"team prefix on the sort key", storage.PlayerQuery{Country: "USA", TeamPrefix: "WNT", Goals: storage.AtLeast(100)},
+			[]string{`country = "USA"`, `sk begins with "WNT"`}, []string{"goals in [100,+inf]"}},
+		{"goals range on the index", storage.PlayerQuery{Country: "USA", TeamPrefix: "WNT", Goals: storage.Between(100, 120)},
+			[]string{`country = "USA"`, "goals in [100,120]"}, []string{`national_team begins with "WNT"`}},
+		{"empty ranges", storage.PlayerQuery{Country: "USA", Goals: &storage.Range{}, Assists: &storage.Range{}, SortBy: storage.SortByGoals},
+			[]string{`country = "USA"`}, nil},
+	}
+	for _, tt := range tests {
+		plan, err := storage.PlanQuery(tt.query, indexes, storage.ReadOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(plan.KeyConditions, tt.keyConditions) || !reflect.DeepEqual(plan.Filters, tt.filters) {
+			t.Errorf("%s: planned key conditions %q and filters %q, want %q and %q", tt.name, plan.KeyConditions, plan.Filters, tt.keyConditions, tt.filters)
+		}
+	}
+}
+
+func TestPlanQueryConsistentRead(t *testing.T) {
+	opts := storage.ReadOptions{ConsistentRead: true}
+	indexes := []storage.IndexDescriptor{
+		{SortBy: storage.SortByPlayer, SortKey: "sk", Consistent: true},
+		{Name: "GSI1", SortBy: storage.SortByGoals, SortKey: "goals"},
+	}
+	plan, err := storage.PlanQuery(storage.PlayerQuery{Country: "USA", Goals: storage.Exactly(10)}, indexes, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Index.Name != "" {
+		t.Errorf("planned %s, want the table", plan)
+	}
+	_, err = storage.PlanQuery(storage.PlayerQuery{Country: "USA", SortBy: storage.SortByGoals}, indexes, opts)
+	if !errors.Is(err, storage.ErrConsistentReadOnIndex) {
+		t.Errorf("sorting by goals: got %v, want ErrConsistentReadOnIndex", err)
+	}
+}
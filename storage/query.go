@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SortField selects the order QueryPlayers returns players in.
+type SortField int
+
+const (
+	// SortAny leaves the order to PlanQuery, which picks the index that
+	// serves the most selective condition.
+	SortAny SortField = iota
+	// SortByPlayer orders by national team, first name and last name.
+	SortByPlayer
+	// SortByGoals orders by goals.
+	SortByGoals
+)
+
+func (f SortField) String() string {
+	switch f {
+	case SortAny:
+		return "any"
+	case SortByPlayer:
+		return "player"
+	case SortByGoals:
+		return "goals"
+	}
+	return fmt.Sprintf("SortField(%d)", int(f))
+}
+
+// PlayerQuery describes a query over the players of one country. Zero fields
+// match everything; all set fields must match.
+type PlayerQuery struct {
+	Country         string
+	TeamPrefix      string
+	FirstNamePrefix string
+	Goals           *Range
+	Assists         *Range
+	Appearances     *Range
+	SortBy          SortField
+	Descending      bool
+}
+
+func (q PlayerQuery) Validate() error {
+	if q.Country == "" {
+		return errors.New("player query needs a country")
+	}
+	if q.SortBy < SortAny || q.SortBy > SortByGoals {
+		return fmt.Errorf("unknown sort field %s", q.SortBy)
+	}
+	for _, r := range []*Range{q.Goals, q.Assists, q.Appearances} {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Matches reports whether record satisfies every condition of the query.
+func (q PlayerQuery) Matches(record *StatsRecord) bool {
+	return record.Country == q.Country &&
+		strings.HasPrefix(record.NationalTeam, q.TeamPrefix) &&
+		strings.HasPrefix(record.FirstName, q.FirstNamePrefix) &&
+		q.Goals.Contains(record.Goals) &&
+		q.Assists.Contains(record.Assists) &&
+		q.Appearances.Contains(record.Appearances)
+}
+
+func (q PlayerQuery) String() string {
+	return fmt.Sprintf("country=%q team_prefix=%q first_name_prefix=%q goals=%s assists=%s appearances=%s sort=%s descending=%t",
+		q.Country, q.TeamPrefix, q.FirstNamePrefix, q.Goals, q.Assists, q.Appearances, q.SortBy, q.Descending)
+}
@@ -2,22 +2,27 @@ package storage
 
 import (
 	"context"
-
-	stats "pagination/storage/dynamo"
 )
 
 type StatsReader interface {
-	ScanStatsTable(ctx context.Context, cursor *stats.Cursor) ([]*stats.StatsRecord, error)
-	ListPlayers(ctx context.Context, country string, nationalTeam string) ([]*stats.StatsRecord, error)
-	ListAllPlayers(ctx context.Context, country string, nationalTeam string) ([]*stats.StatsRecord, error)
-	ListLimitedPlayers(ctx context.Context, country string, nationalTeam string, c *stats.Cursor) ([]*stats.StatsRecord, error)
-	ListPlayersByGoalsThreshold(ctx context.Context, country string, nationalTeam string, goalThreshold int, cursor *stats.Cursor) ([]*stats.StatsRecord, error)
-	ListPlayersByGoalsThresholdSorted(ctx context.Context, country string, nationalTeam string, goalThreshold int, cursor *stats.Cursor) ([]*stats.StatsRecord, error)
-	GetPlayerStats(context.Context, string, string, string, string) (*stats.StatsRecord, error)
+	ScanStatsTable(ctx context.Context, filter ScanFilter, page PageRequest) (*Page, error)
+	ScanAllStats(ctx context.Context, filter ScanFilter, opts ReadOptions, fn func(page *Page) error) error
+	ListPlayers(ctx context.Context, country string, nationalTeam string, opts ReadOptions) ([]*StatsRecord, error)
+	ListAllPlayers(ctx context.Context, country string, nationalTeam string, opts ReadOptions) ([]*StatsRecord, error)
+	StreamAllPlayers(ctx context.Context, country string, nationalTeam string, opts ReadOptions, fn func(record *StatsRecord) error) error
+	ListLimitedPlayers(ctx context.Context, country string, nationalTeam string, page PageRequest) (*Page, error)
+	ListPlayersByGoalsThreshold(ctx context.Context, country string, nationalTeam string, goalThreshold int, page PageRequest) (*Page, error)
+	ListPlayersByGoalsThresholdSorted(ctx context.Context, country string, nationalTeam string, goalThreshold int, page PageRequest) (*Page, error)
+	ListPlayersByGoalsRange(ctx context.Context, country string, nationalTeam string, goals *Range, page PageRequest) (*Page, error)
+	QueryPlayers(ctx context.Context, query PlayerQuery, page PageRequest) (*Page, error)
+	PlanPlayerQuery(query PlayerQuery, opts ReadOptions) (*Plan, error)
+	GetPlayerStats(ctx context.Context, country string, nationalTeam string, firstName string, lastName string, opts ReadOptions) (*StatsRecord, error)
+	CountPlayers(ctx context.Context, country string, nationalTeam string, opts CountOptions) (*Count, error)
+	CountPlayersByGoalsThreshold(ctx context.Context, country string, nationalTeam string, goalThreshold int, opts CountOptions) (*Count, error)
 }
 
 type StatsWriter interface {
-	PutPlayerStats(ctx context.Context, record *stats.StatsRecord) error
+	PutPlayerStats(ctx context.Context, record *StatsRecord) error
 }
 
 type Storage interface {
@@ -0,0 +1,895 @@
+// Package storagetest is a conformance suite for storage.Storage
+// implementations. Every backend runs the same suite so that they keep the
+// same pagination contract.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pagination/storage"
+)
+
+const (
+	womenNationalTeam = "WNT"
+	menNationalTeam   = "MNT"
+)
+
+// Factory returns the storage under test. Backends that share state between
+// calls are fine: every test writes to its own, freshly named countries.
+type Factory func(t *testing.T) storage.Storage
+
+// TokenForger mints a ListPlayersByGoalsThreshold page token for country,
+// nationalTeam and goalThreshold with an arbitrary page limit, standing in for
+// a client that crafts its own tokens.
+type TokenForger func(s storage.Storage, country string, nationalTeam string, goalThreshold int, pageLimit int32) (string, error)
+
+type Option func(*suite)
+
+// WithTokenForger lets the suite check that tokens carrying a page limit no
+// request could have set are rejected. Without it, those checks are skipped.
+func WithTokenForger(forge TokenForger) Option {
+	return func(s *suite) {
+		s.forgeToken = forge
+	}
+}
+
+type suite struct {
+	forgeToken TokenForger
+}
+
+var countrySequence int64
+
+// Run executes the conformance suite against the storage returned by newStorage.
+func Run(t *testing.T, newStorage Factory, opts ...Option) {
+	var cfg suite
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s storage.Storage)
+	}{
+		{"GetPut", testGetPut},
+		{"ListPlayers", testListPlayers},
+		{"StreamAllPlayers", testStreamAllPlayers},
+		{"ListLimitedPlayers", testListLimitedPlayers},
+		{"ListPlayersByGoalsThreshold", testListPlayersByGoalsThreshold},
+		{"ListPlayersByGoalsThresholdSorted", testListPlayersByGoalsThresholdSorted},
+		{"ListPlayersByGoalsRange", testListPlayersByGoalsRange},
+		{"QueryPlayers", testQueryPlayers},
+		{"ScanStatsTable", testScanStatsTable},
+		{"PageTokenReplay", testPageTokenReplay},
+		{"BackwardPagination", testBackwardPagination},
+		{"ReadBudget", testReadBudget},
+		{"LookAhead", testLookAhead},
+		{"InvalidPageLimit", cfg.testInvalidPageLimit},
+		{"Count", testCount},
+		{"Projection", testProjection},
+		{"ConsistentRead", testConsistentRead},
+		{"ForeignExplainer", testForeignExplainer},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStorage(t))
+		})
+	}
+}
+
+// uniqueCountry returns a country no other test writes to.
+func uniqueCountry(t *testing.T) string {
+	return fmt.Sprintf("%s-%d-%d", t.Name(), time.Now().UnixNano(), atomic.AddInt64(&countrySequence, 1))
+}
+
+// seed stores a fixed squad for country and returns it. Goals are distinct so
+// that goals ordering is fully determined.
+func seed(t *testing.T, s storage.Storage, country string) []*storage.StatsRecord {
+	t.Helper()
+	records := []*storage.StatsRecord{
+		{NationalTeam: womenNationalTeam, FirstName: "Alex", LastName: "Morgan", Goals: 119, Assists: 47, Appearances: 200},
+		{NationalTeam: womenNationalTeam, FirstName: "Megan", LastName: "Rapinoe", Goals: 63, Assists: 73, Appearances: 197},
+		{NationalTeam: womenNationalTeam, FirstName: "Carli", LastName: "Lloyd", Goals: 134, Assists: 64, Appearances: 316},
+		{NationalTeam: womenNationalTeam, FirstName: "Mia", LastName: "Hamm", Goals: 158, Assists: 144, Appearances: 276},
+		{NationalTeam: womenNationalTeam, FirstName: "Abby", LastName: "Wambach", Goals: 184, Assists: 75, Appearances: 255},
+		{NationalTeam: womenNationalTeam, FirstName: "Tobin", LastName: "Heath", Goals: 35, Assists: 43, Appearances: 181},
+		{NationalTeam: womenNationalTeam, FirstName: "Kristine", LastName: "Lilly", Goals: 130, Assists: 106, Appearances: 354},
+		{NationalTeam: menNationalTeam, FirstName: "Landon", LastName: "Donovan", Goals: 57, Assists: 58, Appearances: 157},
+		{NationalTeam: menNationalTeam, FirstName: "Clint", LastName: "Dempsey", Goals: 56, Assists: 22, Appearances: 141},
+		{NationalTeam: menNationalTeam, FirstName: "Jozy", LastName: "Altidore", Goals: 42, Assists: 13, Appearances: 115},
+	}
+	for _, record := range records {
+		record.Country = country
+		if err := s.PutPlayerStats(context.Background(), record); err != nil {
+			t.Fatalf("PutPlayerStats(%s %s): %v", record.FirstName, record.LastName, err)
+		}
+	}
+	return records
+}
+
+// team returns the records of a national team in sort key order.
+func team(records []*storage.StatsRecord, nationalTeam string) []*storage.StatsRecord {
+	var selected []*storage.StatsRecord
+	for _, record := range records {
+		if record.NationalTeam == nationalTeam {
+			selected = append(selected, record)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return sortKey(selected[i]) < sortKey(selected[j])
+	})
+	return selected
+}
+
+func sortKey(record *storage.StatsRecord) string {
+	return record.NationalTeam + "#" + record.FirstName + "#" + record.LastName
+}
+
+func withGoalsAtLeast(records []*storage.StatsRecord, goalThreshold int) []*storage.StatsRecord {
+	var selected []*storage.StatsRecord
+	for _, record := range records {
+		if record.Goals >= goalThreshold {
+			selected = append(selected, record)
+		}
+	}
+	return selected
+}
+
+func reversed(records []*storage.StatsRecord) []*storage.StatsRecord {
+	out := make([]*storage.StatsRecord, len(records))
+	for i, record := range records {
+		out[len(records)-1-i] = record
+	}
+	return out
+}
+
+func names(records []*storage.StatsRecord) []string {
+	out := make([]string, 0, len(records))
+	for _, record := range records {
+		out = append(out, record.FirstName+" "+record.LastName)
+	}
+	return out
+}
+
+func assertRecords(t *testing.T, got []*storage.StatsRecord, want []*storage.StatsRecord) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d records %v, want %d records %v", len(got), names(got), len(want), names(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(*got[i], *want[i]) {
+			t.Fatalf("record %d: got %+v, want %+v", i, *got[i], *want[i])
+		}
+	}
+}
+
+type listFunc func(ctx context.Context, page storage.PageRequest) (*storage.Page, error)
+
+// collect follows page tokens until they run out, checking that no page
+// exceeds the page limit.
+func collect(t *testing.T, list listFunc, page storage.PageRequest) []*storage.StatsRecord {
+	t.Helper()
+	var all []*storage.StatsRecord
+	for calls := 0; ; calls++ {
+		if calls > 100 {
+			t.Fatalf("pagination did not terminate")
+		}
+		resp, err := list(context.Background(), page)
+		if err != nil {
+			t.Fatalf("page %d: %v", calls+1, err)
+		}
+		if len(resp.Records) > int(page.PageLimit) {
+			t.Fatalf("page %d: got %d records, page limit is %d", calls+1, len(resp.Records), page.PageLimit)
+		}
+		if int(resp.Count) != len(resp.Records) || resp.ScannedCount < resp.Count {
+			t.Errorf("page %d: Count %d and ScannedCount %d for %d records", calls+1, resp.Count, resp.ScannedCount, len(resp.Records))
+		}
+		if resp.HasMore != (resp.NextPageToken != "") {
+			t.Errorf("page %d: HasMore is %t with next page token %q", calls+1, resp.HasMore, resp.NextPageToken)
+		}
+		all = append(all, resp.Records...)
+		if resp.NextPageToken == "" {
+			return all
+		}
+		page.PageToken = resp.NextPageToken
+	}
+}
+
+func testGetPut(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	country := uniqueCountry(t)
+	records := seed(t, s, country)
+
+	got, err := s.GetPlayerStats(ctx, country, records[0].NationalTeam, records[0].FirstName, records[0].LastName, storage.ReadOptions{})
+	if err != nil {
+		t.Fatalf("GetPlayerStats: %v", err)
+	}
+	assertRecords(t, []*storage.StatsRecord{got}, records[:1])
+
+	updated := *records[0]
+	updated.Goals++
+	if err := s.PutPlayerStats(ctx, &updated); err != nil {
+		t.Fatalf("PutPlayerStats: %v", err)
+	}
+	got, err = s.GetPlayerStats(ctx, country, updated.NationalTeam, updated.FirstName, updated.LastName, storage.ReadOptions{})
+	if err != nil {
+		t.Fatalf("GetPlayerStats after update: %v", err)
+	}
+	assertRecords(t, []*storage.StatsRecord{got}, []*storage.StatsRecord{&updated})
+
+	_, err = s.GetPlayerStats(ctx, country, womenNationalTeam, "No", "One", storage.ReadOptions{})
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetPlayerStats of a missing player: got error %v, want %v", err, storage.ErrNotFound)
+	}
+}
+
+func testListPlayers(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	country := uniqueCountry(t)
+	records := seed(t, s, country)
+
+	for _, nationalTeam := range []string{womenNationalTeam, menNationalTeam} {
+		want := team(records, nationalTeam)
+		got, err := s.ListPlayers(ctx, country, nationalTeam, storage.ReadOptions{})
+		if err != nil {
+			t.Fatalf("ListPlayers(%s): %v", nationalTeam, err)
+		}
+		assertRecords(t, got, want)
+		got, err = s.ListAllPlayers(ctx, country, nationalTeam, storage.ReadOptions{})
+		if err != nil {
+			t.Fatalf("ListAllPlayers(%s): %v", nationalTeam, err)
+		}
+		assertRecords(t, got, want)
+	}
+
+	got, err := s.ListAllPlayers(ctx, uniqueCountry(t), womenNationalTeam, storage.ReadOptions{})
+	if err != nil {
+		t.Fatalf("ListAllPlayers of an empty country: %v", err)
+	}
+	assertRecords(t, got, nil)
+}
+
+func testStreamAllPlayers(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	country := uniqueCountry(t)
+	want := team(seed(t, s, country), womenNationalTeam)
+
+	var got []*storage.StatsRecord
+	err := s.StreamAllPlayers(ctx, country, womenNationalTeam, storage.ReadOptions{}, func(record *storage.StatsRecord) error {
+		got = append(got, record)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamAllPlayers: %v", err)
+	}
+	assertRecords(t, got, want)
+
+	stop := errors.New("stop")
+	calls := 0
+	err = s.StreamAllPlayers(ctx, country, womenNationalTeam, storage.ReadOptions{}, func(record *storage.StatsRecord) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("stream stopped by its callback: got error %v after %d calls, want %v after 1", err, calls, stop)
+	}
+}
+
+func testListLimitedPlayers(t *testing.T, s storage.Storage) {
+	country := uniqueCountry(t)
+	want := team(seed(t, s, country), womenNationalTeam)
+
+	for limit := int32(1); limit <= int32(len(want))+1; limit++ {
+		t.Run(fmt.Sprintf("PageLimit=%d", limit), func(t *testing.T) {
+			got := collect(t, func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+				return s.ListLimitedPlayers(ctx, country, womenNationalTeam, page)
+			}, storage.PageRequest{PageLimit: limit, ScanIndexForward: true})
+			assertRecords(t, got, want)
+		})
+	}
+}
+
+func testListPlayersByGoalsThreshold(t *testing.T, s storage.Storage) {
+	country := uniqueCountry(t)
+	goalThreshold := 100
+	want := withGoalsAtLeast(team(seed(t, s, country), womenNationalTeam), goalThreshold)
+
+	for _, forward := range []bool{true, false} {
+		expected := want
+		if !forward {
+			expected = reversed(want)
+		}
+		for limit := int32(1); limit <= int32(len(want))+1; limit++ {
+			t.Run(fmt.Sprintf("ScanIndexForward=%t/PageLimit=%d", forward, limit), func(t *testing.T) {
+				got := collect(t, func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+					return s.ListPlayersByGoalsThreshold(ctx, country, womenNationalTeam, goalThreshold, page)
+				}, storage.PageRequest{PageLimit: limit, ScanIndexForward: forward})
+				assertRecords(t, got, expected)
+			})
+		}
+	}
+}
+
+func testListPlayersByGoalsThresholdSorted(t *testing.T, s storage.Storage) {
+	country := uniqueCountry(t)
+	goalThreshold := 60
+	want := withGoalsAtLeast(team(seed(t, s, country), womenNationalTeam), goalThreshold)
+	sort.Slice(want, func(i, j int) bool {
+		return want[i].Goals < want[j].Goals
+	})
+
+	for _, forward := range []bool{true, false} {
+		expected := want
+		if !forward {
+			expected = reversed(want)
+		}
+		for limit := int32(1); limit <= int32(len(want))+1; limit++ {
+			t.Run(fmt.Sprintf("ScanIndexForward=%t/PageLimit=%d", forward, limit), func(t *testing.T) {
+				got := collect(t, func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+					return s.ListPlayersByGoalsThresholdSorted(ctx, country, womenNationalTeam, goalThreshold, page)
+				}, storage.PageRequest{PageLimit: limit, ScanIndexForward: forward})
+				assertRecords(t, got, expected)
+			})
+		}
+	}
+}
+
+func testListPlayersByGoalsRange(t *testing.T, s storage.Storage) {
+	country := uniqueCountry(t)
+	players := team(seed(t, s, country), womenNationalTeam)
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].Goals < players[j].Goals
+	})
+
+	ranges := []*storage.Range{
+		storage.Between(60, 134),
+		storage.LessThan(119),
+		storage.AtMost(119),
+		storage.GreaterThan(134),
+		storage.Exactly(63),
+		nil,
+	}
+	for _, goalsRange := range ranges {
+		var want []*storage.StatsRecord
+		for _, record := range players {
+			if goalsRange.Contains(record.Goals) {
+				want = append(want, record)
+			}
+		}
+		for _, forward := range []bool{true, false} {
+			expected := want
+			if !forward {
+				expected = reversed(want)
+			}
+			for limit := int32(1); limit <= 3; limit++ {
+				goalsRange := goalsRange
+				t.Run(fmt.Sprintf("Goals=%s/ScanIndexForward=%t/PageLimit=%d", goalsRange, forward, limit), func(t *testing.T) {
+					got := collect(t, func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+						return s.ListPlayersByGoalsRange(ctx, country, womenNationalTeam, goalsRange, page)
+					}, storage.PageRequest{PageLimit: limit, ScanIndexForward: forward})
+					assertRecords(t, got, expected)
+				})
+			}
+		}
+	}
+
+	if _, err := s.ListPlayersByGoalsRange(context.Background(), country, womenNationalTeam, storage.Between(10, 5), storage.PageRequest{PageLimit: 1}); err == nil {
+		t.Errorf("empty range: expected an error")
+	}
+}
+
+func testQueryPlayers(t *testing.T, s storage.Storage) {
+	country := uniqueCountry(t)
+	records := seed(t, s, country)
+	queries := []storage.PlayerQuery{
+		{Country: country, TeamPrefix: "W"},
+		{Country: country, TeamPrefix: womenNationalTeam, Goals: storage.AtLeast(100), SortBy: storage.SortByGoals, Descending: true},
+		{Country: country, Goals: storage.Between(40, 120)},
+		{Country: country, FirstNamePrefix: "M", SortBy: storage.SortByPlayer},
+		{Country: country, Assists: storage.AtLeast(60), Appearances: storage.AtMost(300), SortBy: storage.SortByGoals},
+	}
+	for _, query := range queries {
+		var want []*storage.StatsRecord
+		for _, record := range records {
+			if query.Matches(record) {
+				want = append(want, record)
+			}
+		}
+		plan, err := s.PlanPlayerQuery(query, storage.ReadOptions{})
+		if err != nil {
+			t.Fatalf("PlanPlayerQuery(%s): %v", query, err)
+		}
+		byGoals := plan.Index.SortBy == storage.SortByGoals
+		sort.Slice(want, func(i, j int) bool {
+			if byGoals && want[i].Goals != want[j].Goals {
+				return want[i].Goals < want[j].Goals
+			}
+			return sortKey(want[i]) < sortKey(want[j])
+		})
+		if query.Descending {
+			want = reversed(want)
+		}
+		for limit := int32(1); limit <= 3; limit++ {
+			query := query
+			t.Run(fmt.Sprintf("%s/PageLimit=%d", strings.TrimPrefix(query.String(), fmt.Sprintf("country=%q ", country)), limit), func(t *testing.T) {
+				got := collect(t, func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+					return s.QueryPlayers(ctx, query, page)
+				}, storage.PageRequest{PageLimit: limit})
+				assertRecords(t, got, want)
+			})
+		}
+	}
+
+	for _, query := range []storage.PlayerQuery{
+		{TeamPrefix: womenNationalTeam},
+		{Country: country, Goals: storage.Between(10, 5)},
+		{Country: country, TeamPrefix: womenNationalTeam + "#Alex"},
+	} {
+		if _, err := s.QueryPlayers(context.Background(), query, storage.PageRequest{PageLimit: 1}); err == nil {
+			t.Errorf("QueryPlayers(%s): expected an error", query)
+		}
+	}
+}
+
+// byIdentity orders records independently of backend scan order.
+func byIdentity(records []*storage.StatsRecord) []*storage.StatsRecord {
+	sorted := append([]*storage.StatsRecord(nil), records...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Country != sorted[j].Country {
+			return sorted[i].Country < sorted[j].Country
+		}
+		return sortKey(sorted[i]) < sortKey(sorted[j])
+	})
+	return sorted
+}
+
+func testScanStatsTable(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	// the scan filters on a national team no other test uses, so that the
+	// suite also works against a shared table
+	nationalTeam := uniqueCountry(t)
+	var want []*storage.StatsRecord
+	filter := storage.ScanFilter{NationalTeam: nationalTeam, Goals: storage.AtLeast(60), Appearances: storage.AtMost(300)}
+	for _, country := range []string{uniqueCountry(t), uniqueCountry(t)} {
+		for _, record := range seed(t, s, country) {
+			copied := *record
+			copied.NationalTeam = nationalTeam
+			if err := s.PutPlayerStats(ctx, &copied); err != nil {
+				t.Fatalf("PutPlayerStats: %v", err)
+			}
+			if filter.Matches(&copied) {
+				want = append(want, &copied)
+			}
+		}
+	}
+	want = byIdentity(want)
+
+	for _, limit := range []int32{1, 2, 3, int32(len(want)), int32(len(want)) + 1} {
+		t.Run(fmt.Sprintf("PageLimit=%d", limit), func(t *testing.T) {
+			got := collect(t, func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+				return s.ScanStatsTable(ctx, filter, page)
+			}, storage.PageRequest{PageLimit: limit, ScanIndexForward: true})
+			assertRecords(t, byIdentity(got), want)
+		})
+	}
+
+	var streamed []*storage.StatsRecord
+	err := s.ScanAllStats(ctx, filter, storage.ReadOptions{}, func(page *storage.Page) error {
+		streamed = append(streamed, page.Records...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanAllStats: %v", err)
+	}
+	assertRecords(t, byIdentity(streamed), want)
+}
+
+func testPageTokenReplay(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	country := uniqueCountry(t)
+	otherCountry := uniqueCountry(t)
+	seed(t, s, country)
+	seed(t, s, otherCountry)
+
+	resp, err := s.ListPlayersByGoalsThreshold(ctx, country, womenNationalTeam, 100, storage.PageRequest{PageLimit: 1, ScanIndexForward: true})
+	if err != nil {
+		t.Fatalf("ListPlayersByGoalsThreshold: %v", err)
+	}
+	if resp.NextPageToken == "" {
+		t.Fatalf("expected a next page token")
+	}
+	page := storage.PageRequest{PageLimit: 1, ScanIndexForward: true, PageToken: resp.NextPageToken}
+
+	if _, err := s.ListPlayersByGoalsThreshold(ctx, otherCountry, womenNationalTeam, 100, page); !errors.Is(err, storage.ErrInvalidPageToken) {
+		t.Errorf("token replayed against another country: got error %v, want %v", err, storage.ErrInvalidPageToken)
+	}
+	if _, err := s.ListPlayersByGoalsThreshold(ctx, country, womenNationalTeam, 50, page); !errors.Is(err, storage.ErrInvalidPageToken) {
+		t.Errorf("token replayed against another threshold: got error %v, want %v", err, storage.ErrInvalidPageToken)
+	}
+	if _, err := s.ListPlayersByGoalsThresholdSorted(ctx, country, womenNationalTeam, 100, page); !errors.Is(err, storage.ErrInvalidPageToken) {
+		t.Errorf("token replayed against another index: got error %v, want %v", err, storage.ErrInvalidPageToken)
+	}
+	if _, err := s.ListLimitedPlayers(ctx, country, womenNationalTeam, storage.PageRequest{PageToken: "not-a-token"}); !errors.Is(err, storage.ErrInvalidPageToken) {
+		t.Errorf("malformed token: got error %v, want %v", err, storage.ErrInvalidPageToken)
+	}
+}
+
+func testBackwardPagination(t *testing.T, s storage.Storage) {
+	country := uniqueCountry(t)
+	seed(t, s, country)
+	queries := map[string]listFunc{
+		"ListLimitedPlayers": func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+			return s.ListLimitedPlayers(ctx, country, womenNationalTeam, page)
+		},
+		"ListPlayersByGoalsThresholdSorted": func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+			return s.ListPlayersByGoalsThresholdSorted(ctx, country, womenNationalTeam, 30, page)
+		},
+	}
+	for name, list := range queries {
+		for _, forward := range []bool{true, false} {
+			for limit := int32(1); limit <= 3; limit++ {
+				list := list
+				t.Run(fmt.Sprintf("%s/ScanIndexForward=%t/PageLimit=%d", name, forward, limit), func(t *testing.T) {
+					ctx := context.Background()
+					page := storage.PageRequest{PageLimit: limit, ScanIndexForward: forward}
+					var pages []*storage.Page
+					for {
+						resp, err := list(ctx, page)
+						if err != nil {
+							t.Fatalf("page %d: %v", len(pages)+1, err)
+						}
+						if len(resp.Records) > 0 {
+							pages = append(pages, resp)
+						}
+						if resp.NextPageToken == "" {
+							break
+						}
+						page.PageToken = resp.NextPageToken
+					}
+					if len(pages) < 2 {
+						t.Fatalf("got %d pages, the test needs at least 2", len(pages))
+					}
+					if pages[0].PrevPageToken != "" {
+						t.Errorf("first page has a previous page token")
+					}
+
+					current := pages[len(pages)-1]
+					for i := len(pages) - 2; i >= 0; i-- {
+						if current.PrevPageToken == "" {
+							t.Fatalf("page %d has no previous page token", i+2)
+						}
+						page.PageToken = current.PrevPageToken
+						resp, err := list(ctx, page)
+						if err != nil {
+							t.Fatalf("going back to page %d: %v", i+1, err)
+						}
+						assertRecords(t, resp.Records, pages[i].Records)
+						current = resp
+					}
+					// like a full last page, a full first page reached going
+					// back may carry a token that leads to an empty page
+					if current.PrevPageToken != "" {
+						page.PageToken = current.PrevPageToken
+						resp, err := list(ctx, page)
+						if err != nil {
+							t.Fatalf("going back past the first page: %v", err)
+						}
+						if len(resp.Records) != 0 {
+							t.Errorf("got %d records before the first page", len(resp.Records))
+						}
+					}
+					if current.NextPageToken == "" {
+						t.Errorf("first page reached going back has no next page token")
+					}
+				})
+			}
+		}
+	}
+}
+
+func testReadBudget(t *testing.T, s storage.Storage) {
+	country := uniqueCountry(t)
+	records := seed(t, s, country)
+	// both queries evaluate every seeded item of their key range but match
+	// only a few, so a budget of two items cannot fill a page
+	threshold := withGoalsAtLeast(team(records, womenNationalTeam), 150)
+	sorted := team(records, menNationalTeam)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Goals < sorted[j].Goals
+	})
+	tests := []struct {
+		name string
+		list listFunc
+		want []*storage.StatsRecord
+	}{
+		{"ListPlayersByGoalsThreshold", func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+			return s.ListPlayersByGoalsThreshold(ctx, country, womenNationalTeam, 150, page)
+		}, threshold},
+		{"ListPlayersByGoalsThresholdSorted", func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+			return s.ListPlayersByGoalsThresholdSorted(ctx, country, menNationalTeam, 30, page)
+		}, sorted},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			exhausted := 0
+			list := func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+				resp, err := tt.list(ctx, page)
+				if err == nil && resp.BudgetExhausted {
+					exhausted++
+					if resp.NextPageToken == "" {
+						t.Errorf("page with an exhausted budget has no next page token")
+					}
+				}
+				return resp, err
+			}
+			got := collect(t, list, storage.PageRequest{PageLimit: 3, ScanIndexForward: true, MaxScanned: 2})
+			assertRecords(t, got, tt.want)
+			if exhausted == 0 {
+				t.Errorf("no page reported an exhausted budget")
+			}
+		})
+	}
+}
+
+func (cfg suite) testInvalidPageLimit(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	country := uniqueCountry(t)
+	seed(t, s, country)
+	lists := map[string]listFunc{
+		"ListLimitedPlayers": func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+			return s.ListLimitedPlayers(ctx, country, womenNationalTeam, page)
+		},
+		"ListPlayersByGoalsThreshold": func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+			return s.ListPlayersByGoalsThreshold(ctx, country, womenNationalTeam, 100, page)
+		},
+		"ListPlayersByGoalsThresholdSorted": func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+			return s.ListPlayersByGoalsThresholdSorted(ctx, country, womenNationalTeam, 100, page)
+		},
+	}
+	for name, list := range lists {
+		for _, page := range []storage.PageRequest{
+			{PageLimit: 0},
+			{PageLimit: 0, LookAhead: true},
+			{PageLimit: -1, BatchSize: 5},
+			{PageLimit: 1, BatchSize: -1},
+			{PageLimit: 1, MaxBatchSize: -1},
+			{PageLimit: 1, MaxScanned: -1},
+			{PageLimit: 1, MaxReadCapacity: -1},
+		} {
+			if _, err := list(ctx, page); err == nil {
+				t.Errorf("%s with %+v: expected an error", name, page)
+			}
+		}
+	}
+	if cfg.forgeToken == nil {
+		return
+	}
+	token, err := cfg.forgeToken(s, country, womenNationalTeam, 100, 0)
+	if err != nil {
+		t.Fatalf("forging a token: %v", err)
+	}
+	for _, lookAhead := range []bool{false, true} {
+		page := storage.PageRequest{PageLimit: 1, PageToken: token, LookAhead: lookAhead}
+		if _, err := s.ListPlayersByGoalsThreshold(ctx, country, womenNationalTeam, 100, page); !errors.Is(err, storage.ErrInvalidPageToken) {
+			t.Errorf("token with a zero page limit (look-ahead %t): got error %v, want %v", lookAhead, err, storage.ErrInvalidPageToken)
+		}
+	}
+}
+
+func testLookAhead(t *testing.T, s storage.Storage) {
+	country := uniqueCountry(t)
+	records := seed(t, s, country)
+	sorted := withGoalsAtLeast(team(records, womenNationalTeam), 60)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Goals < sorted[j].Goals
+	})
+	tests := []struct {
+		name string
+		list listFunc
+		want []*storage.StatsRecord // in ascending order
+	}{
+		{"ListLimitedPlayers", func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+			return s.ListLimitedPlayers(ctx, country, womenNationalTeam, page)
+		}, team(records, womenNationalTeam)},
+		{"ListPlayersByGoalsThreshold", func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+			return s.ListPlayersByGoalsThreshold(ctx, country, womenNationalTeam, 100, page)
+		}, withGoalsAtLeast(team(records, womenNationalTeam), 100)},
+		{"ListPlayersByGoalsThresholdSorted", func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+			return s.ListPlayersByGoalsThresholdSorted(ctx, country, womenNationalTeam, 60, page)
+		}, sorted},
+	}
+	for _, tt := range tests {
+		for limit := int32(1); limit <= int32(len(tt.want))+1; limit++ {
+			tt, limit := tt, limit
+			t.Run(fmt.Sprintf("%s/PageLimit=%d", tt.name, limit), func(t *testing.T) {
+				ctx := context.Background()
+				page := storage.PageRequest{PageLimit: limit, ScanIndexForward: true, LookAhead: true}
+				var pages []*storage.Page
+				var got []*storage.StatsRecord
+				for {
+					if len(pages) > len(tt.want) {
+						t.Fatalf("pagination did not terminate")
+					}
+					resp, err := tt.list(ctx, page)
+					if err != nil {
+						t.Fatalf("page %d: %v", len(pages)+1, err)
+					}
+					if len(resp.Records) == 0 {
+						t.Fatalf("page %d is empty", len(pages)+1)
+					}
+					pages = append(pages, resp)
+					got = append(got, resp.Records...)
+					if resp.NextPageToken == "" {
+						break
+					}
+					page.PageToken = resp.NextPageToken
+				}
+				assertRecords(t, got, tt.want)
+
+				// going back ends exactly at the first page as well
+				current := pages[len(pages)-1]
+				for i := len(pages) - 2; i >= 0; i-- {
+					page.PageToken = current.PrevPageToken
+					resp, err := tt.list(ctx, page)
+					if err != nil {
+						t.Fatalf("going back to page %d: %v", i+1, err)
+					}
+					assertRecords(t, resp.Records, pages[i].Records)
+					current = resp
+				}
+				if current.PrevPageToken != "" {
+					t.Errorf("first page reached going back has a previous page token")
+				}
+			})
+		}
+	}
+}
+
+func testCount(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	country := uniqueCountry(t)
+	records := seed(t, s, country)
+	wantTeam := int64(len(team(records, womenNationalTeam)))
+	wantThreshold := int64(len(withGoalsAtLeast(team(records, womenNationalTeam), 100)))
+
+	got, err := s.CountPlayers(ctx, country, womenNationalTeam, storage.CountOptions{})
+	if err != nil {
+		t.Fatalf("CountPlayers: %v", err)
+	}
+	if got.Count != wantTeam || !got.Exact {
+		t.Errorf("CountPlayers = %d (exact %t), want exactly %d", got.Count, got.Exact, wantTeam)
+	}
+	got, err = s.CountPlayersByGoalsThreshold(ctx, country, womenNationalTeam, 100, storage.CountOptions{})
+	if err != nil {
+		t.Fatalf("CountPlayersByGoalsThreshold: %v", err)
+	}
+	if got.Count != wantThreshold || !got.Exact {
+		t.Errorf("CountPlayersByGoalsThreshold = %d (exact %t), want exactly %d", got.Count, got.Exact, wantThreshold)
+	}
+
+	for _, nationalTeam := range []string{"", "W", womenNationalTeam, womenNationalTeam + "#A"} {
+		listed := collect(t, func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+			return s.ListPlayersByGoalsThreshold(ctx, country, nationalTeam, 100, page)
+		}, storage.PageRequest{PageLimit: 5})
+		for _, consistent := range []bool{false, true} {
+			got, err := s.CountPlayersByGoalsThreshold(ctx, country, nationalTeam, 100, storage.CountOptions{ConsistentRead: consistent})
+			if err != nil {
+				t.Fatalf("CountPlayersByGoalsThreshold(%q): %v", nationalTeam, err)
+			}
+			if got.Count != int64(len(listed)) {
+				t.Errorf("CountPlayersByGoalsThreshold(%q) = %d (consistent %t), ListPlayersByGoalsThreshold returned %d", nationalTeam, got.Count, consistent, len(listed))
+			}
+		}
+	}
+
+	got, err = s.CountPlayers(ctx, country, womenNationalTeam, storage.CountOptions{MaxScanned: 3})
+	if err != nil {
+		t.Fatalf("CountPlayers with a budget: %v", err)
+	}
+	if got.Exact || got.Count > wantTeam || got.ScannedCount > 3 {
+		t.Errorf("CountPlayers with a budget of 3 = %d (exact %t, %d scanned), want a lower bound of %d", got.Count, got.Exact, got.ScannedCount, wantTeam)
+	}
+}
+
+func testProjection(t *testing.T, s storage.Storage) {
+	country := uniqueCountry(t)
+	fields := storage.Fields{storage.FieldFirstName, storage.FieldLastName, storage.FieldGoals}
+	var want []*storage.StatsRecord
+	for _, record := range withGoalsAtLeast(team(seed(t, s, country), womenNationalTeam), 100) {
+		want = append(want, &storage.StatsRecord{FirstName: record.FirstName, LastName: record.LastName, Goals: record.Goals})
+	}
+	sort.Slice(want, func(i, j int) bool {
+		return want[i].Goals > want[j].Goals
+	})
+
+	got := collect(t, func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+		resp, err := s.ListPlayersByGoalsThresholdSorted(ctx, country, womenNationalTeam, 100, page)
+		if err == nil && !reflect.DeepEqual(resp.Fields, fields) {
+			t.Errorf("page reports fields %s, want %s", resp.Fields, fields)
+		}
+		return resp, err
+	}, storage.PageRequest{PageLimit: 2, Fields: fields})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	_, err := s.ListLimitedPlayers(context.Background(), country, womenNationalTeam, storage.PageRequest{PageLimit: 2, Fields: storage.Fields{"nickname"}})
+	if err == nil {
+		t.Errorf("unknown field: expected an error")
+	}
+}
+
+func testConsistentRead(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	opts := storage.ReadOptions{ConsistentRead: true}
+	country := uniqueCountry(t)
+	records := seed(t, s, country)
+
+	got, err := s.GetPlayerStats(ctx, country, womenNationalTeam, "Alex", "Morgan", opts)
+	if err != nil {
+		t.Fatalf("GetPlayerStats: %v", err)
+	}
+	if !reflect.DeepEqual(got, records[0]) {
+		t.Errorf("GetPlayerStats = %+v, want %+v", got, records[0])
+	}
+	all, err := s.ListAllPlayers(ctx, country, womenNationalTeam, opts)
+	if err != nil {
+		t.Fatalf("ListAllPlayers: %v", err)
+	}
+	assertRecords(t, all, team(records, womenNationalTeam))
+	listed := collect(t, func(ctx context.Context, page storage.PageRequest) (*storage.Page, error) {
+		return s.ListLimitedPlayers(ctx, country, womenNationalTeam, page)
+	}, storage.PageRequest{PageLimit: 2, ConsistentRead: true})
+	assertRecords(t, listed, team(records, womenNationalTeam))
+
+	_, err = s.ListPlayersByGoalsThresholdSorted(ctx, country, womenNationalTeam, 100, storage.PageRequest{PageLimit: 2, ConsistentRead: true})
+	if !errors.Is(err, storage.ErrConsistentReadOnIndex) {
+		t.Errorf("ListPlayersByGoalsThresholdSorted: got %v, want ErrConsistentReadOnIndex", err)
+	}
+	plan, err := s.PlanPlayerQuery(storage.PlayerQuery{Country: country, Goals: storage.Between(100, 150)}, opts)
+	if err != nil {
+		t.Fatalf("PlanPlayerQuery: %v", err)
+	}
+	if plan.Index.Name != "" {
+		t.Errorf("PlanPlayerQuery planned %s, want the table", plan)
+	}
+	count, err := s.CountPlayersByGoalsThreshold(ctx, country, womenNationalTeam, 100, storage.CountOptions{ConsistentRead: true})
+	if err != nil {
+		t.Fatalf("CountPlayersByGoalsThreshold: %v", err)
+	}
+	if want := int64(len(withGoalsAtLeast(team(records, womenNationalTeam), 100))); count.Count != want {
+		t.Errorf("CountPlayersByGoalsThreshold = %d, want %d", count.Count, want)
+	}
+}
+
+// foreignExplainer is an Explainer no backend knows how to record into.
+type foreignExplainer struct{}
+
+func (foreignExplainer) String() string { return "foreign" }
+
+func testForeignExplainer(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	country := uniqueCountry(t)
+	seed(t, s, country)
+
+	_, err := s.GetPlayerStats(ctx, country, womenNationalTeam, "Alex", "Morgan", storage.ReadOptions{Explain: foreignExplainer{}})
+	if !errors.Is(err, storage.ErrExplainUnsupported) {
+		t.Errorf("GetPlayerStats: got %v, want ErrExplainUnsupported", err)
+	}
+	_, err = s.ListLimitedPlayers(ctx, country, womenNationalTeam, storage.PageRequest{PageLimit: 2, Explain: foreignExplainer{}})
+	if !errors.Is(err, storage.ErrExplainUnsupported) {
+		t.Errorf("ListLimitedPlayers: got %v, want ErrExplainUnsupported", err)
+	}
+	_, err = s.CountPlayers(ctx, country, womenNationalTeam, storage.CountOptions{Explain: foreignExplainer{}})
+	if !errors.Is(err, storage.ErrExplainUnsupported) {
+		t.Errorf("CountPlayers: got %v, want ErrExplainUnsupported", err)
+	}
+}
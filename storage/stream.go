@@ -0,0 +1,27 @@
+package storage
+
+import "context"
+
+// StreamPlayers runs StreamAllPlayers in the background and delivers its
+// records over an unbuffered channel. The producer blocks until each record is
+// received, so a slow consumer holds back the reads instead of letting records
+// pile up. The record channel is closed when the stream ends; the error
+// channel then yields the stream's error, or nil. Cancel ctx to abandon the
+// stream early.
+func StreamPlayers(ctx context.Context, reader StatsReader, country string, nationalTeam string, opts ReadOptions) (<-chan *StatsRecord, <-chan error) {
+	records := make(chan *StatsRecord)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(records)
+		errs <- reader.StreamAllPlayers(ctx, country, nationalTeam, opts, func(record *StatsRecord) error {
+			select {
+			case records <- record:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return records, errs
+}
@@ -0,0 +1,40 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"pagination/storage"
+	"pagination/storage/memory"
+)
+
+func TestStreamPlayers(t *testing.T) {
+	s := memory.New()
+	seedPlayers(t, s, 20)
+	records, errs := storage.StreamPlayers(context.Background(), s, "Country2", "WNT", storage.ReadOptions{})
+	count := 0
+	for range records {
+		count++
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if count != 4 {
+		t.Fatalf("got %d records, want 4", count)
+	}
+}
+
+func TestStreamPlayersCancel(t *testing.T) {
+	s := memory.New()
+	seedPlayers(t, s, 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	records, errs := storage.StreamPlayers(ctx, s, "Country2", "WNT", storage.ReadOptions{})
+	<-records
+	cancel()
+	for range records {
+	}
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+}
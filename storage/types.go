@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var (
+	ErrNotFound         = errors.New("record not found")
+	ErrInvalidPageToken = errors.New("invalid page token")
+)
+
+// StatsRecord is a player's national team record, independent of how a
+// backend keys or stores it.
+type StatsRecord struct {
+	Country      string
+	NationalTeam string
+	FirstName    string
+	LastName     string
+	Goals        int
+	Assists      int
+	Appearances  int
+}
+
+// PageRequest selects a page of a paginated call. PageToken is empty for the
+// first page and otherwise holds the token returned with the previous page, in
+// which case the limit and ordering encoded in the token take precedence.
+//
+// PageLimit is the number of records a page holds. BatchSize is how many items
+// a backend reads per request while filling the page and defaults to
+// PageLimit; when a filter rejects most of what is read, the batch size grows
+// with the filter's hit ratio up to MaxBatchSize, which defaults to 1000. Set
+// MaxBatchSize to BatchSize to read in fixed batches. Both are read from every
+// request rather than from the page token, and backends that do not read in
+// batches ignore them.
+//
+// MaxScanned and MaxReadCapacity bound the work a single List* call does: once
+// it has evaluated MaxScanned items or consumed MaxReadCapacity read capacity
+// units, it returns what matched so far with BudgetExhausted set and a token
+// that resumes after the last item read. The capacity budget is checked after
+// each request, so a call can overshoot it by one batch. Zero means no limit;
+// backends that do not meter capacity ignore MaxReadCapacity.
+//
+// Without LookAhead, a page that fills up carries a next page token even when
+// no matching records follow, so the last page fetched may be empty. With
+// LookAhead, the List* queries read until they find one match beyond the page,
+// and NextPageToken is empty exactly when nothing follows, unless the read
+// budget ran out first.
+//
+// Fields restricts the records to a subset of their fields, so that backends
+// read less; nil reads whole records.
+//
+// ConsistentRead makes reads of the table strongly consistent, so they see
+// every write that completed before them. Reads that need the goals index fail
+// with ErrConsistentReadOnIndex instead, while QueryPlayers with SortAny falls
+// back to the table.
+//
+// Explain records the requests the call sends to the backend, see Explainer.
+type PageRequest struct {
+	PageLimit        int32
+	ScanIndexForward bool
+	PageToken        string
+	BatchSize        int32
+	MaxBatchSize     int32
+	MaxScanned       int32
+	MaxReadCapacity  float64
+	LookAhead        bool
+	Fields           Fields
+	ConsistentRead   bool
+	Explain          Explainer
+}
+
+// Page is a single page of results. NextPageToken is empty once there are no
+// further pages, PrevPageToken on the first page. Passing PrevPageToken back
+// returns the page that precedes this one, in the same order.
+//
+// BudgetExhausted reports that the call stopped at its read budget, so the
+// page may hold fewer than PageLimit records, or none, even though more may
+// follow.
+//
+// HasMore reports whether the page has a NextPageToken. It is false once the
+// backend knows the results ended; without LookAhead, a page that fills up
+// just before the end may still report it, and its token then leads to an
+// empty page. Count is the number of records on the page and ScannedCount the
+// number of items the backend evaluated to fill it, before filtering.
+// ConsumedCapacity is nil for backends that do not meter reads. Fields is the
+// projection the records were read with; fields outside it are zero, and nil
+// means the records are complete.
+type Page struct {
+	Records          []*StatsRecord
+	NextPageToken    string
+	PrevPageToken    string
+	BudgetExhausted  bool
+	HasMore          bool
+	Count            int32
+	ScannedCount     int32
+	ConsumedCapacity *ConsumedCapacity
+	Fields           Fields
+}
+
+// ConsumedCapacity is the read capacity a call consumed, in total and split by
+// the table and each of its indexes.
+type ConsumedCapacity struct {
+	CapacityUnits float64
+	Table         float64
+	Indexes       map[string]float64
+}
+
+// Range bounds an integer attribute inclusively; a nil end is unbounded.
+type Range struct {
+	Min *int
+	Max *int
+}
+
+func AtLeast(n int) *Range {
+	return &Range{Min: &n}
+}
+
+func AtMost(n int) *Range {
+	return &Range{Max: &n}
+}
+
+func Between(lower int, upper int) *Range {
+	return &Range{Min: &lower, Max: &upper}
+}
+
+// LessThan and GreaterThan exclude n; as the bounded attributes are integers,
+// they translate into inclusive bounds one step inside n.
+func LessThan(n int) *Range {
+	return AtMost(n - 1)
+}
+
+func GreaterThan(n int) *Range {
+	return AtLeast(n + 1)
+}
+
+func Exactly(n int) *Range {
+	return Between(n, n)
+}
+
+// Validate rejects ranges that cannot contain any value.
+func (r *Range) Validate() error {
+	if r != nil && r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		return fmt.Errorf("empty range %s", r)
+	}
+	return nil
+}
+
+// Contains reports whether n lies within the range. A nil range contains every
+// value.
+func (r *Range) Contains(n int) bool {
+	if r == nil {
+		return true
+	}
+	return (r.Min == nil || n >= *r.Min) && (r.Max == nil || n <= *r.Max)
+}
+
+func (r *Range) String() string {
+	if r == nil {
+		return "any"
+	}
+	lower, upper := "-inf", "+inf"
+	if r.Min != nil {
+		lower = strconv.Itoa(*r.Min)
+	}
+	if r.Max != nil {
+		upper = strconv.Itoa(*r.Max)
+	}
+	return "[" + lower + "," + upper + "]"
+}
+
+// ScanFilter restricts a table scan to matching records; zero fields match
+// everything. A non-zero TotalSegments splits the table into that many
+// disjoint segments and restricts the scan to the one numbered Segment.
+type ScanFilter struct {
+	NationalTeam  string
+	Goals         *Range
+	Assists       *Range
+	Appearances   *Range
+	Segment       int32
+	TotalSegments int32
+}
+
+func (f ScanFilter) Validate() error {
+	if f.TotalSegments < 0 || f.TotalSegments > 0 && (f.Segment < 0 || f.Segment >= f.TotalSegments) {
+		return fmt.Errorf("invalid scan segment %d of %d", f.Segment, f.TotalSegments)
+	}
+	return nil
+}
+
+// Matches reports whether record passes the attribute filters. Segments are
+// left to the backend.
+func (f ScanFilter) Matches(record *StatsRecord) bool {
+	return (f.NationalTeam == "" || record.NationalTeam == f.NationalTeam) &&
+		f.Goals.Contains(record.Goals) &&
+		f.Assists.Contains(record.Assists) &&
+		f.Appearances.Contains(record.Appearances)
+}
+
+func (f ScanFilter) String() string {
+	return fmt.Sprintf("national_team=%q goals=%s assists=%s appearances=%s segment=%d/%d", f.NationalTeam, f.Goals, f.Assists, f.Appearances, f.Segment, f.TotalSegments)
+}
+
+// CountOptions configures the count APIs. By default every matching item is
+// counted. Setting MaxScanned or MaxReadCapacity makes the count approximate:
+// it stops once the budget is spent and reports a lower bound. With
+// ConsistentRead, CountPlayersByGoalsThreshold counts on the table instead of
+// the goals index. Explain records the requests the count sends.
+type CountOptions struct {
+	MaxScanned      int32
+	MaxReadCapacity float64
+	ConsistentRead  bool
+	Explain         Explainer
+}
+
+// Count is the result of a count API. Exact is false when counting stopped at
+// the read budget, in which case Count is a lower bound.
+type Count struct {
+	Count            int64
+	Exact            bool
+	ScannedCount     int64
+	ConsumedCapacity *ConsumedCapacity
+}